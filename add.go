@@ -0,0 +1,328 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fetchTimeout is how long fetching a snippet from a URL may take.
+const fetchTimeout = 30 * time.Second
+
+// importFile is a file to import as a snippet, in the folder it goes to.
+type importFile struct {
+	path   string
+	folder string
+}
+
+// expandImports returns the files to import for the arguments of `nap add`.
+// Arguments may be files, directories, which are imported recursively into a
+// folder named after them, or glob patterns, where ** matches any number of
+// directories. Hidden and ignored files and directories are skipped when
+// walking and matching globs, while files named explicitly are imported.
+func expandImports(args []string, folder string, ignore ignoreRules) ([]importFile, error) {
+	var files []importFile
+	for _, arg := range args {
+		var paths []string
+		glob := true
+		switch {
+		case strings.Contains(arg, "**"):
+			matches, err := globRecursive(arg)
+			if err != nil {
+				return nil, err
+			}
+			paths = matches
+		case strings.ContainsAny(arg, "*?["):
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, err
+			}
+			paths = matches
+		default:
+			paths = []string{arg}
+			glob = false
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no files match %s", arg)
+		}
+
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil {
+				return nil, err
+			}
+			if glob && ignore.ignored(filepath.ToSlash(filepath.Clean(p)), info.IsDir()) {
+				continue
+			}
+			if !info.IsDir() {
+				files = append(files, importFile{p, folder})
+				continue
+			}
+			dirFolder := folder
+			if dirFolder == "" {
+				abs, err := filepath.Abs(p)
+				if err != nil {
+					return nil, err
+				}
+				dirFolder = filepath.Base(abs)
+			}
+			err = filepath.WalkDir(p, func(file string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if file == p {
+					return nil
+				}
+				rel, err := filepath.Rel(p, file)
+				if err != nil {
+					return err
+				}
+				if strings.HasPrefix(d.Name(), ".") || ignore.ignored(filepath.ToSlash(rel), d.IsDir()) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() {
+					files = append(files, importFile{file, dirFolder})
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// globRecursive returns the files matching the pattern, where ** matches any
+// number of directories.
+func globRecursive(pattern string) ([]string, error) {
+	root, rest, _ := strings.Cut(filepath.ToSlash(pattern), "**")
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		root = "."
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	if rest == "" {
+		rest = "*"
+	}
+	if _, err := path.Match(rest, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		// ** matches the leading directories of any depth
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		for i := range parts {
+			if ok, _ := path.Match(rest, strings.Join(parts[i:], "/")); ok {
+				matches = append(matches, file)
+				break
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// nameOf returns the name and language of a snippet saved from the file
+// name, the language being the extension of the name. Files without
+// extension, like Dockerfile or .bashrc, keep their name and have no
+// language.
+func nameOf(file string) (string, string) {
+	if file == "" {
+		return defaultSnippetName, defaultLanguage
+	}
+	ext := filepath.Ext(file)
+	if ext == file {
+		// a leading dot is part of the name
+		ext = ""
+	}
+	return strings.TrimSuffix(file, ext), strings.TrimPrefix(ext, ".")
+}
+
+// fetchURL returns the content served at the URL, failing past limit bytes
+// when limit is positive.
+func fetchURL(rawURL string, limit int64) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL %s, only http and https are", rawURL)
+	}
+	client := http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: %s", rawURL, resp.Status)
+	}
+	return readLimited(resp.Body, resp.ContentLength, limit)
+}
+
+// runAdd runs the `nap add` subcommand, importing files or a URL as snippets,
+// and returns the exit code.
+func runAdd(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	save := addSaveFlags(flags)
+	folder := flags.String("folder", "", "folder of the snippets, instead of misc or the name of the directory")
+	fromURL := flags.String("from-url", "", "fetch the snippet from an http or https URL")
+	args, err := parseInterspersed(flags, args)
+	if err != nil || !save.valid() {
+		return 2
+	}
+	if (*fromURL == "" && len(args) == 0) || (*fromURL != "" && len(args) > 1) {
+		fmt.Println("usage: nap add [--folder <folder>] <file|dir|glob...>")
+		fmt.Println("       nap add --from-url <url> [folder/name.ext]")
+		return 2
+	}
+	if outsideHome(*folder) {
+		fmt.Printf("folder %s is outside the home directory\n", *folder)
+		return 1
+	}
+	if len(args) == 1 && *fromURL != "" && outsideHome(args[0]) {
+		fmt.Printf("%s is outside the home directory\n", args[0])
+		return 1
+	}
+
+	if *fromURL != "" {
+		content, err := fetchURL(*fromURL, config.MaxStdinSize)
+		if err != nil {
+			fmt.Printf("could not fetch %s: %v\n", *fromURL, err)
+			return 1
+		}
+		binary := isBinary(content)
+		if binary && !*save.attach {
+			fmt.Printf("%s looks binary, pass --attach to store it as an attachment\n", *fromURL)
+			return 1
+		}
+		u, _ := url.Parse(*fromURL)
+		base := path.Base(u.Path)
+		if base == "/" || base == "." {
+			base = ""
+		}
+		name, language := nameOf(base)
+		f := defaultFolder(*folder)
+		if len(args) == 1 {
+			var argLanguage string
+			f, name, argLanguage = parseName(args[0])
+			if *folder != "" && !strings.Contains(args[0], "/") {
+				f = *folder
+			}
+			// the language comes from the URL unless the name has an extension
+			if filepath.Ext(args[0]) != "" {
+				language = argLanguage
+			}
+		}
+		snippet := save.newSnippet(f, name, language)
+		if base == "" && len(args) == 0 {
+			snippet = uniqueSnippet(snippet, func(path string) bool { return snippetPathTaken(config, snippets, path) })
+		}
+		snippet.Source = *fromURL
+		snippet.Attachment = binary
+		return addSnippets(config, snippets, save, []Snippet{snippet}, []string{content})
+	}
+
+	files, err := expandImports(args, *folder, readIgnore(config))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	var added []Snippet
+	var contents []string
+	sources := map[string]string{}
+	code := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file.path)
+		if err != nil {
+			fmt.Printf("could not read %s: %v\n", file.path, err)
+			return 1
+		}
+		binary := isBinary(string(content))
+		if binary && !*save.attach {
+			fmt.Printf("skipping %s, it looks binary, pass --attach to store it as an attachment\n", file.path)
+			code = 1
+			continue
+		}
+		name, language := nameOf(filepath.Base(file.path))
+		snippet := save.newSnippet(defaultFolder(file.folder), name, language)
+		snippet.Attachment = binary
+		if abs, err := filepath.Abs(file.path); err == nil {
+			snippet.Source = abs
+		}
+		// files of nested directories land in the same folder
+		if other, ok := sources[snippet.Path()]; ok {
+			fmt.Printf("%s and %s would both be added as %s, add them separately with --folder\n", other, file.path, snippet)
+			return 1
+		}
+		sources[snippet.Path()] = file.path
+		added = append(added, snippet)
+		contents = append(contents, string(content))
+	}
+	if addCode := addSnippets(config, snippets, save, added, contents); addCode != 0 {
+		code = addCode
+	}
+	return code
+}
+
+// defaultFolder returns the folder, or the default folder if it is empty.
+func defaultFolder(folder string) string {
+	if folder == "" {
+		return defaultSnippetFolder
+	}
+	return folder
+}
+
+// addSnippets stores the snippets with their contents, printing what was
+// added, and returns the exit code.
+func addSnippets(config Config, snippets []Snippet, save saveFlags, added []Snippet, contents []string) int {
+	code := 0
+	modified := false
+	for i, snippet := range added {
+		var saved bool
+		var err error
+		snippets, snippet, saved, err = storeSnippet(config, snippets, snippet, contents[i], save)
+		switch {
+		case err != nil:
+			printSaveError(snippet, err)
+			code = 1
+		case !saved:
+			fmt.Printf("%s already exists, leaving it as is\n", snippet)
+		default:
+			fmt.Printf("added %s\n", snippet)
+			modified = true
+		}
+	}
+	if modified {
+		writeSnippets(config, snippets)
+	}
+	return code
+}
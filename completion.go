@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// subcommands are the commands completed as the first argument of nap.
+var subcommands = []string{"list", "show", "diff", "dedupe", "scan", "add", "materialize", "use", "run", "pick", "trash", "shell-init", "completion", "--session", "--help"}
+
+// completions are the scripts completing nap with `nap __complete`, by shell.
+var completions = map[string]string{
+	"bash": `_nap() {
+  local IFS=$'\n'
+  COMPREPLY=($(nap __complete "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+  if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+    compopt -o nospace
+  fi
+}
+complete -F _nap nap
+`,
+	"zsh": `#compdef nap
+_nap() {
+  local -a candidates
+  candidates=(${(f)"$(nap __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+  compadd -S '' -- ${(M)candidates:#*/}
+  compadd -- ${candidates:#*/}
+}
+compdef _nap nap
+`,
+	"fish": `complete -c nap -f -a '(nap __complete (commandline -opc)[2..-1] (commandline -ct))'
+`,
+}
+
+// runCompletion runs the `nap completion` subcommand, printing the completion
+// script of the given shell.
+func runCompletion(args []string) {
+	if len(args) == 1 {
+		if script, ok := completions[args[0]]; ok {
+			fmt.Print(script)
+			return
+		}
+	}
+	fmt.Println("usage: nap completion bash|zsh|fish")
+}
+
+// runComplete runs the hidden `nap __complete` subcommand used by the
+// completion scripts. The arguments are the words of the command line after
+// nap, the last one being completed, and the candidates are printed one per
+// line.
+func runComplete(args []string, config Config, snippets []Snippet) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	current := args[len(args)-1]
+	for _, candidate := range completeArgs(args[:len(args)-1], config, snippets) {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+}
+
+// completeArgs returns the candidates for the argument following args.
+func completeArgs(args []string, config Config, snippets []Snippet) []string {
+	if len(args) == 0 {
+		return append(slices.Clone(subcommands), snippetCandidates(snippets)...)
+	}
+	previous := args[len(args)-1]
+	switch args[0] {
+	case "list":
+		if previous == "--sort" {
+			return sortModes
+		}
+		return []string{"--sort"}
+	case "show":
+		if previous == "--lines" || previous == "--file" {
+			return nil
+		}
+		return append([]string{"--lines", "--file", "--exact", "--raw"}, snippetCandidates(snippets)...)
+	case "use":
+		if previous == "--set" || slices.ContainsFunc(args[1:], func(arg string) bool { return !strings.HasPrefix(arg, "-") }) {
+			return nil
+		}
+		return append([]string{"--exact", "--force", "--set"}, snippetCandidates(snippets)...)
+	case "materialize":
+		if slices.ContainsFunc(args[1:], func(arg string) bool { return !strings.HasPrefix(arg, "-") }) {
+			return nil
+		}
+		return append([]string{"--exact", "--force"}, snippetCandidates(snippets)...)
+	case "dedupe":
+		if previous == "--threshold" {
+			return nil
+		}
+		return []string{"-y", "--threshold"}
+	case "diff":
+		return append([]string{"--exact"}, snippetCandidates(snippets)...)
+	case "scan":
+		return []string{"--dry-run"}
+	case "add":
+		switch previous {
+		case "--folder":
+			var folders []string
+			for _, s := range snippets {
+				if !slices.Contains(folders, s.Folder) {
+					folders = append(folders, s.Folder)
+				}
+			}
+			return folders
+		case "--from-url", "--tags", "--desc", "--lang":
+			return nil
+		}
+		return []string{"--folder", "--from-url", "--append", "--force", "--no-clobber", "--tags", "--desc", "--lang", "--fav", "--attach"}
+	case "run":
+		if slices.Contains(args, "--") || slices.ContainsFunc(args[1:], func(arg string) bool { return !strings.HasPrefix(arg, "-") }) {
+			return nil
+		}
+		return append([]string{"-y"}, snippetCandidates(snippets)...)
+	case "trash":
+		if len(args) == 1 {
+			return []string{"list", "restore", "empty"}
+		}
+		if args[1] == "restore" {
+			var candidates []string
+			for _, t := range readTrash(config) {
+				candidates = append(candidates, t.String())
+			}
+			return candidates
+		}
+	case "shell-init", "completion":
+		if len(args) == 1 {
+			shells := maps.Keys(completions)
+			slices.Sort(shells)
+			return shells
+		}
+	case "--session":
+		if len(args) == 1 {
+			sessions := maps.Keys(readState().Sessions)
+			slices.Sort(sessions)
+			return sessions
+		}
+	}
+	return nil
+}
+
+// snippetCandidates returns the folder/ and folder/name.ext of the snippets.
+func snippetCandidates(snippets []Snippet) []string {
+	var folders, paths []string
+	for _, s := range snippets {
+		if folder := s.Folder + "/"; !slices.Contains(folders, folder) {
+			folders = append(folders, folder)
+		}
+		paths = append(paths, s.String())
+	}
+	return append(folders, paths...)
+}
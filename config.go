@@ -9,6 +9,8 @@ import (
 
 	"github.com/adrg/xdg"
 	"github.com/caarlos0/env/v6"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,8 +24,24 @@ type Config struct {
 
 	DefaultLanguage string `env:"NAP_DEFAULT_LANGUAGE" yaml:"default_language"`
 
+	// TrashRetention is the number of days deleted snippets are kept in the
+	// trash before being purged. Zero keeps them forever.
+	TrashRetention int `env:"NAP_TRASH_RETENTION" yaml:"trash_retention"`
+
+	// MaxStdinSize is the largest content in bytes saved from stdin or
+	// fetched from a URL. Zero saves any size.
+	MaxStdinSize int64 `env:"NAP_MAX_STDIN_SIZE" yaml:"max_stdin_size"`
+
 	Theme string `env:"NAP_THEME" yaml:"theme"`
 
+	// Runners are the commands running snippets, by language, split into
+	// arguments like a shell would.
+	Runners map[string]string `yaml:"runners"`
+
+	// Ignore are the globs of the files that are not snippets, in the
+	// gitignore syntax like the .napignore file of the home directory.
+	Ignore []string `env:"NAP_IGNORE" envSeparator:"," yaml:"ignore"`
+
 	PrimaryColor        string `env:"NAP_PRIMARY_COLOR" yaml:"primary_color"`
 	PrimaryColorSubdued string `env:"NAP_PRIMARY_COLOR_SUBDUED" yaml:"primary_color_subdued"`
 	BrightGreenColor    string `env:"NAP_BRIGHT_GREEN" yaml:"bright_green"`
@@ -41,7 +59,11 @@ func newConfig() Config {
 		Home:                defaultHome(),
 		File:                "snippets.json",
 		DefaultLanguage:     defaultLanguage,
+		TrashRetention:      30,
+		MaxStdinSize:        defaultMaxStdinSize,
 		Theme:               "catppuccin-mocha",
+		Runners:             maps.Clone(defaultRunners),
+		Ignore:              slices.Clone(defaultIgnore),
 		PrimaryColor:        "#74c7ec",
 		PrimaryColorSubdued: "#94e2d5",
 		BrightGreenColor:    "#f9e2af",
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+)
+
+// horizontalStep is the number of columns scrolled horizontally at a time.
+const horizontalStep = 8
+
+const (
+	resetStyle   = "\x1b[0m"
+	reverseStyle = "\x1b[7m"
+	noReverse    = "\x1b[27m"
+)
+
+// contentView is the text shown in the content pane, which can be searched,
+// wrapped and scrolled horizontally.
+type contentView struct {
+	// the styled lines of the content and their text without the styling.
+	lines []string
+	text  []string
+	// whether the lines are numbered in the line numbers pane.
+	numbered bool
+	// whether long lines wrap rather than being cut at the pane width.
+	wrap bool
+	// the first column shown when long lines are cut.
+	left int
+	// the line of the cursor and, when selecting lines, the line the
+	// selection started from.
+	cursor    int
+	anchor    int
+	selecting bool
+	// the search, its matches and the index of the current match.
+	query   string
+	matches []contentMatch
+	match   int
+	// the first row of each line and the number of rows, as last rendered.
+	starts []int
+	rows   int
+}
+
+// contentMatch is a match of the search between the runes start and end of a
+// line.
+type contentMatch struct{ line, start, end int }
+
+// setContent replaces the content with the styled text, searching it again.
+func (v *contentView) setContent(s string, numbered bool) {
+	s = strings.ReplaceAll(s, "\t", strings.Repeat(" ", tabSpaces))
+	v.lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	v.text = make([]string, len(v.lines))
+	for i, line := range v.lines {
+		v.text[i] = stripANSI(line)
+	}
+	v.numbered = numbered
+	v.left = 0
+	if v.cursor >= len(v.lines) {
+		v.cursor = len(v.lines) - 1
+		v.selecting = false
+	}
+	v.search(v.query)
+}
+
+// clear removes the content, when something else is shown in the pane.
+func (v *contentView) clear() {
+	v.lines, v.text, v.matches, v.starts = nil, nil, nil, nil
+	v.rows = 0
+	v.cursor = 0
+	v.selecting = false
+}
+
+// selection returns the first and last of the selected lines, which is the
+// cursor line when not selecting.
+func (v *contentView) selection() (int, int) {
+	if !v.selecting {
+		return v.cursor, v.cursor
+	}
+	if v.anchor < v.cursor {
+		return v.anchor, v.cursor
+	}
+	return v.cursor, v.anchor
+}
+
+// search finds the matches of the query in the content. The search ignores
+// case unless the query has upper case letters.
+func (v *contentView) search(query string) {
+	v.query = query
+	v.matches = nil
+	v.match = 0
+	if query == "" {
+		return
+	}
+	fold := strings.ToLower(query) == query
+	for i, text := range v.text {
+		if fold {
+			text = strings.ToLower(text)
+		}
+		for offset := 0; ; {
+			j := strings.Index(text[offset:], query)
+			if j < 0 {
+				break
+			}
+			start := utf8.RuneCountInString(text[:offset+j])
+			v.matches = append(v.matches, contentMatch{i, start, start + utf8.RuneCountInString(query)})
+			offset += j + len(query)
+		}
+	}
+}
+
+// matchStatus describes the current match of the search.
+func (v *contentView) matchStatus() string {
+	if len(v.matches) == 0 {
+		return "no matches"
+	}
+	return fmt.Sprintf("%d/%d", v.match+1, len(v.matches))
+}
+
+// render returns the rows showing the content in a pane of the width, with
+// the matches of the search in reverse video.
+func (v *contentView) render(width int) string {
+	var b strings.Builder
+	v.starts = make([]int, 0, len(v.lines))
+	v.rows = 0
+	left := v.left
+	if v.wrap {
+		left = 0
+	}
+	next := 0
+	for i, line := range v.lines {
+		v.starts = append(v.starts, v.rows)
+		var ranges []contentMatch
+		for next < len(v.matches) && v.matches[next].line == i {
+			ranges = append(ranges, v.matches[next])
+			next++
+		}
+		for _, row := range layoutLine(line, ranges, left, width, v.wrap) {
+			b.WriteString(row + "\n")
+			v.rows++
+		}
+	}
+	return b.String()
+}
+
+// lineNumbers returns the line numbers of the rows as last rendered, leaving
+// the rows continuing a wrapped line blank. The numbers of the selected lines
+// are in reverse video.
+func (v *contentView) lineNumbers() string {
+	var b strings.Builder
+	first, last := v.selection()
+	for i, start := range v.starts {
+		end := v.rows
+		if i+1 < len(v.starts) {
+			end = v.starts[i+1]
+		}
+		if i >= first && i <= last {
+			b.WriteString(fmt.Sprintf(reverseStyle+"%3d "+noReverse+"\n", i+1))
+		} else {
+			b.WriteString(fmt.Sprintf("%3d \n", i+1))
+		}
+		b.WriteString(strings.Repeat("    \n", end-start-1))
+	}
+	return b.String() + "  ~ \n"
+}
+
+// lineAt returns the line shown on the row.
+func (v *contentView) lineAt(row int) int {
+	line := sort.Search(len(v.starts), func(i int) bool { return v.starts[i] > row }) - 1
+	if line < 0 {
+		return 0
+	}
+	return line
+}
+
+// layoutLine returns the rows showing the styled line from the column left,
+// with the runes in the ranges in reverse video. The line is cut into rows of
+// the width when wrap is set and cut at the width otherwise. Each row repeats
+// the styling in effect at its start, so that it can be rendered on its own.
+func layoutLine(line string, ranges []contentMatch, left, width int, wrap bool) []string {
+	var rows []string
+	var row strings.Builder
+	var active []string
+	reversed := false
+	index, col, rowWidth := 0, 0, 0
+	endRow := func() {
+		if reversed || len(active) > 0 {
+			row.WriteString(resetStyle)
+		}
+		rows = append(rows, row.String())
+		row.Reset()
+		rowWidth = 0
+	}
+
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			seq := line[i : i+n]
+			i += n
+			if seq == resetStyle || seq == "\x1b[m" {
+				active = active[:0]
+			} else if strings.HasSuffix(seq, "m") {
+				active = append(active, seq)
+			}
+			row.WriteString(seq)
+			if reversed {
+				row.WriteString(reverseStyle)
+			}
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+		w := runewidth.RuneWidth(r)
+		marked := inMatch(ranges, index)
+		index++
+		if col < left {
+			col += w
+			continue
+		}
+		col += w
+
+		if width > 0 && rowWidth+w > width {
+			if !wrap {
+				break
+			}
+			endRow()
+			for _, seq := range active {
+				row.WriteString(seq)
+			}
+			if reversed {
+				row.WriteString(reverseStyle)
+			}
+		}
+		if marked != reversed {
+			if marked {
+				row.WriteString(reverseStyle)
+			} else {
+				row.WriteString(noReverse)
+			}
+			reversed = marked
+		}
+		row.WriteRune(r)
+		rowWidth += w
+	}
+	endRow()
+	return rows
+}
+
+// inMatch reports whether the rune at the index is in one of the ranges.
+func inMatch(ranges []contentMatch, index int) bool {
+	for _, r := range ranges {
+		if index >= r.start && index < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeLen returns the length of the terminal escape sequence at the start of
+// s, or 0 if s does not start with one.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
+
+// stripANSI returns the text of s without its escape sequences.
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// showContent displays the content in the content pane, with its line
+// numbers.
+func (m *Model) showContent() {
+	m.Code.SetContent(m.content.render(m.Code.Width))
+	if m.content.numbered {
+		m.LineNumbers.SetContent(m.content.lineNumbers())
+	} else {
+		m.LineNumbers.SetContent("")
+	}
+	m.syncLineNumbers()
+}
+
+// showLineNumbers updates the line numbers after the cursor or the selection
+// moved.
+func (m *Model) showLineNumbers() {
+	if m.content.numbered {
+		m.LineNumbers.SetContent(m.content.lineNumbers())
+	}
+	m.syncLineNumbers()
+}
+
+// syncLineNumbers scrolls the line numbers along with the content.
+func (m *Model) syncLineNumbers() {
+	m.LineNumbers.SetYOffset(m.Code.YOffset)
+}
+
+// findInContent searches the content for the query, showing the first match
+// from the top of the pane.
+func (m *Model) findInContent(query string) tea.Cmd {
+	m.content.search(query)
+	m.showContent()
+	if query == "" {
+		return nil
+	}
+	if len(m.content.matches) == 0 {
+		return m.List().NewStatusMessage("No matches for " + query)
+	}
+	top := m.content.lineAt(m.Code.YOffset)
+	for i, match := range m.content.matches {
+		if match.line >= top {
+			m.content.match = i
+			break
+		}
+	}
+	m.showMatch()
+	return nil
+}
+
+// nextMatch moves to the next match of the search in the direction of step,
+// wrapping around the content.
+func (m *Model) nextMatch(step int) {
+	n := len(m.content.matches)
+	if n == 0 {
+		return
+	}
+	m.content.match = ((m.content.match+step)%n + n) % n
+	m.showMatch()
+}
+
+// showMatch scrolls the content to show the current match.
+func (m *Model) showMatch() {
+	match := m.content.matches[m.content.match]
+	if !m.content.wrap && (match.start < m.content.left || match.end > m.content.left+m.Code.Width) {
+		m.content.left = match.start - m.Code.Width/2
+		if m.content.left < 0 {
+			m.content.left = 0
+		}
+		m.showContent()
+	}
+	row := m.content.starts[match.line]
+	if row < m.Code.YOffset || row >= m.Code.YOffset+m.Code.Height {
+		m.Code.SetYOffset(row - m.Code.Height/2)
+	}
+	m.content.cursor = match.line
+	m.showLineNumbers()
+}
+
+// gotoLine scrolls the content to show the line, numbered from 1, at the top
+// of the pane.
+func (m *Model) gotoLine(value string) tea.Cmd {
+	line, err := strconv.Atoi(value)
+	if err != nil || line < 1 || line > len(m.content.lines) {
+		return m.List().NewStatusMessage(fmt.Sprintf("No line %q", value))
+	}
+	m.Code.SetYOffset(m.content.starts[line-1])
+	m.content.cursor = line - 1
+	m.showLineNumbers()
+	return nil
+}
+
+// toggleWrap switches between wrapping long lines and cutting them, keeping
+// the line at the top of the pane.
+func (m *Model) toggleWrap() {
+	top := m.content.lineAt(m.Code.YOffset)
+	m.content.wrap = !m.content.wrap
+	m.content.left = 0
+	m.showContent()
+	m.Code.SetYOffset(m.content.starts[top])
+	m.syncLineNumbers()
+}
+
+// scrollHorizontal scrolls the content by the number of columns.
+func (m *Model) scrollHorizontal(columns int) {
+	m.content.left += columns
+	if m.content.left < 0 {
+		m.content.left = 0
+	}
+	m.showContent()
+}
+
+// moveCursor moves the cursor by the number of lines, scrolling the content to
+// keep it in view.
+func (m *Model) moveCursor(lines int) {
+	m.content.cursor += lines
+	if m.content.cursor < 0 {
+		m.content.cursor = 0
+	}
+	if last := len(m.content.lines) - 1; m.content.cursor > last {
+		m.content.cursor = last
+	}
+	row, end := m.content.starts[m.content.cursor], m.content.rows
+	if m.content.cursor+1 < len(m.content.starts) {
+		end = m.content.starts[m.content.cursor+1]
+	}
+	if row < m.Code.YOffset {
+		m.Code.SetYOffset(row)
+	} else if end > m.Code.YOffset+m.Code.Height {
+		m.Code.SetYOffset(end - m.Code.Height)
+	}
+	m.showLineNumbers()
+}
+
+// keepCursorInView moves the cursor into view after the content scrolled.
+func (m *Model) keepCursorInView() {
+	if len(m.content.starts) == 0 {
+		return
+	}
+	top := m.content.lineAt(m.Code.YOffset)
+	if m.content.starts[top] < m.Code.YOffset && top+1 < len(m.content.starts) {
+		top++
+	}
+	bottom := m.content.lineAt(m.Code.YOffset + m.Code.Height - 1)
+	if bottom < top {
+		bottom = top
+	}
+	if m.content.cursor < top {
+		m.content.cursor = top
+	} else if m.content.cursor > bottom {
+		m.content.cursor = bottom
+	}
+	m.showLineNumbers()
+}
+
+// toggleSelection starts selecting lines from the cursor, or stops selecting.
+func (m *Model) toggleSelection() {
+	m.content.selecting = !m.content.selecting
+	m.content.anchor = m.content.cursor
+	m.showLineNumbers()
+}
+
+// contentLines returns the lines first to last shown in the content pane: the
+// lines of the file shown, which is the active file of a multi-file snippet,
+// or the rendered lines of a Markdown file.
+func (m *Model) contentLines(first, last int) (string, error) {
+	if !m.content.numbered {
+		if last >= len(m.content.text) {
+			return "", fmt.Errorf("line %d is past the end of the content", last+1)
+		}
+		return strings.Join(m.content.text[first:last+1], "\n") + "\n", nil
+	}
+	content, err := os.ReadFile(m.selectedSnippetFilePath())
+	if err != nil {
+		return "", err
+	}
+	return lineRange(string(content), first+1, last+1)
+}
+
+// yankLines copies the selected lines of the snippet, or the cursor line, to
+// the clipboard.
+func (m *Model) yankLines() tea.Cmd {
+	first, last := m.content.selection()
+	m.content.selecting = false
+	m.showLineNumbers()
+
+	snippet := m.selectedSnippet()
+	content, err := m.contentLines(first, last)
+	if err == nil {
+		err = clipboard.WriteAll(content)
+	}
+	if err != nil {
+		return m.List().NewStatusMessage(fmt.Sprintf("Unable to copy lines: %v", err))
+	}
+	m.recordUsage([]Snippet{snippet}, usageCopy)
+	if first == last {
+		return m.List().NewStatusMessage(fmt.Sprintf("Copied line %d", first+1))
+	}
+	return m.List().NewStatusMessage(fmt.Sprintf("Copied lines %d-%d", first+1, last+1))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestContentView(t *testing.T) {
+	var v contentView
+	v.setContent("\x1b[31mfoo\x1b[0m bar\nlong line with Foo\n\tfoo\n", true)
+
+	v.search("foo")
+	if len(v.matches) != 3 || v.matches[1] != (contentMatch{1, 15, 18}) {
+		t.Logf("lower case searches should ignore case: got %v", v.matches)
+		t.FailNow()
+	}
+	v.search("Foo")
+	if len(v.matches) != 1 || v.matchStatus() != "1/1" {
+		t.Logf("upper case searches should match case: got %v", v.matches)
+		t.FailNow()
+	}
+
+	v.search("bar")
+	rows := strings.Split(v.render(0), "\n")
+	if want := "\x1b[31mfoo\x1b[0m \x1b[7mbar\x1b[0m"; rows[0] != want {
+		t.Logf("expected match in reverse video %q, got %q", want, rows[0])
+		t.FailNow()
+	}
+	if rows[2] != "    foo" {
+		t.Logf("expected tabs to be expanded, got %q", rows[2])
+		t.FailNow()
+	}
+
+	v.search("")
+	v.left = 5
+	rows = strings.Split(v.render(4), "\n")
+	if stripANSI(rows[0]) != "ar" || stripANSI(rows[1]) != "line" {
+		t.Logf("expected lines to be scrolled and cut, got %q", rows)
+		t.FailNow()
+	}
+
+	v.wrap = true
+	rows = strings.Split(v.render(4), "\n")
+	if len(rows) != v.rows+1 || v.rows != 9 || v.starts[1] != 2 || v.starts[2] != 7 {
+		t.Logf("expected long lines to wrap, got %q with starts %v", rows, v.starts)
+		t.FailNow()
+	}
+	if rows[0] != "\x1b[31mfoo\x1b[0m " || rows[1] != "bar" {
+		t.Logf("expected wrapped rows to keep their styling, got %q", rows[:2])
+		t.FailNow()
+	}
+	numbers := strings.Split(v.lineNumbers(), "\n")
+	if len(numbers) != len(rows)+1 || numbers[2] != "  2 " || numbers[3] != "    " {
+		t.Logf("expected line numbers in sync with the rows, got %q", numbers)
+		t.FailNow()
+	}
+	if v.lineAt(4) != 1 || v.lineAt(7) != 2 {
+		t.Logf("wrong line at rows 4 and 7: %d %d", v.lineAt(4), v.lineAt(7))
+		t.FailNow()
+	}
+}
+
+func TestContentPane(t *testing.T) {
+	tmp := tmpHome(t)
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.MkdirAll(filepath.Join(tmp, "misc"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "misc", "lines.txt"), []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m.updateContentView(updateContentMsg(m.selectedSnippet()))
+	m.pane = contentPane
+
+	m.findInContent("line 7")
+	if got := m.content.matchStatus(); got != "1/11" {
+		t.Logf("expected 11 matches, got %s", got)
+		t.FailNow()
+	}
+	m.nextMatch(-1)
+	if m.Code.YOffset == 0 || m.LineNumbers.YOffset != m.Code.YOffset {
+		t.Logf("expected the last match to be scrolled to with the line numbers, got %d and %d", m.Code.YOffset, m.LineNumbers.YOffset)
+		t.FailNow()
+	}
+
+	m.gotoLine("42")
+	if m.Code.YOffset != 41 || m.LineNumbers.YOffset != 41 {
+		t.Logf("expected line 42 at the top, got offsets %d and %d", m.Code.YOffset, m.LineNumbers.YOffset)
+		t.FailNow()
+	}
+	m.toggleWrap()
+	if !m.content.wrap || m.Code.YOffset != 41 {
+		t.Logf("expected wrapping to keep line 42 at the top, got offset %d", m.Code.YOffset)
+		t.FailNow()
+	}
+	m.toggleSelection()
+	m.moveCursor(3)
+	m.moveCursor(-5)
+	if first, last := m.content.selection(); first != 39 || last != 41 {
+		t.Logf("expected lines 40-42 to be selected, got %d-%d", first+1, last+1)
+		t.FailNow()
+	}
+	if got, err := m.contentLines(m.content.selection()); err != nil || got != "line 40\nline 41\nline 42\n" {
+		t.Logf("expected lines 40-42 to be copied, got %q: %v", got, err)
+		t.FailNow()
+	}
+	if m.Code.YOffset != 39 || m.LineNumbers.YOffset != 39 {
+		t.Logf("expected the content to scroll up to the cursor, got offsets %d and %d", m.Code.YOffset, m.LineNumbers.YOffset)
+		t.FailNow()
+	}
+	if numbers := strings.Split(m.LineNumbers.View(), "\n"); !strings.Contains(numbers[0], reverseStyle) || strings.Contains(numbers[3], reverseStyle) {
+		t.Logf("expected the selected line numbers to be highlighted, got %q", numbers[:4])
+		t.FailNow()
+	}
+}
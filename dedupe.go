@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"golang.org/x/exp/slices"
+)
+
+// defaultSimilarity is how similar the normalized contents of snippets must
+// be, from 0 to 1, for them to be near duplicates.
+const defaultSimilarity = 0.9
+
+// contentHash returns the hash identifying the content of a snippet file.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeContent returns the lines of the content with their whitespace
+// collapsed, leaving out blank lines, so that formatting changes do not
+// matter when comparing snippets.
+func normalizeContent(content string) []string {
+	var lines []string
+	for _, line := range splitLines(content) {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			lines = append(lines, strings.Join(fields, " "))
+		}
+	}
+	return lines
+}
+
+// similarity returns how similar the lines are, from 0 when they have no line
+// in common to 1 when they are the same.
+func similarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	equal := 0
+	for _, line := range diffLines(a, b) {
+		if line.op == diffEqual {
+			equal++
+		}
+	}
+	return 2 * float64(equal) / float64(len(a)+len(b))
+}
+
+// findDuplicate returns a snippet with the same content, ignoring empty
+// snippets. The content is compared with the hashes of the snippets, and only
+// the file of a matching snippet is read, to check it did not change since.
+func findDuplicate(config Config, snippets []Snippet, content []byte) (Snippet, bool) {
+	if len(content) == 0 {
+		return Snippet{}, false
+	}
+	hash := contentHash(content)
+	for _, s := range snippets {
+		if s.Hash != hash {
+			continue
+		}
+		if b, err := readSnippet(config, s); err == nil && contentHash(b) == hash {
+			return s, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// hashSnippet sets the hash of the snippet to that of its content, leaving
+// it as is when the snippet cannot be read.
+func hashSnippet(config Config, s *Snippet) {
+	if b, err := readSnippet(config, *s); err == nil {
+		s.Hash = contentHash(b)
+	}
+}
+
+// rehashSnippet updates the hash of the snippet at path after its file was
+// written from the TUI.
+func (m *Model) rehashSnippet(path string) {
+	folder, idx, ok := m.locateSnippet(path)
+	if !ok {
+		return
+	}
+	s := m.Lists[folder].Items()[idx].(Snippet)
+	hashSnippet(m.config, &s)
+	m.Lists[folder].SetItem(idx, s)
+}
+
+// duplicateGroup is a group of snippets with the same or similar contents.
+type duplicateGroup struct {
+	snippets []Snippet
+	// exact is set when the snippets have the very same content.
+	exact bool
+}
+
+// findDuplicates groups the snippets with the same content, and those of the
+// same language whose normalized contents are at least as similar as the
+// threshold. Empty snippets are left out. The snippets of each group are
+// listed favorites first, then oldest first.
+func findDuplicates(config Config, snippets []Snippet, threshold float64) []duplicateGroup {
+	type entry struct {
+		snippet Snippet
+		hash    string
+		lines   []string
+	}
+	var entries []entry
+	for _, s := range snippets {
+		b, err := readSnippet(config, s)
+		if err != nil || len(b) == 0 {
+			continue
+		}
+		entries = append(entries, entry{s, contentHash(b), normalizeContent(string(b))})
+	}
+
+	group := make([]int, len(entries))
+	for i := range group {
+		group[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if group[i] != i {
+			group[i] = root(group[i])
+		}
+		return group[i]
+	}
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			a, b := entries[i], entries[j]
+			if root(i) == root(j) {
+				continue
+			}
+			near := threshold < 1 && a.snippet.Language == b.snippet.Language &&
+				// the similarity cannot exceed the ratio of the line counts
+				2*float64(min(len(a.lines), len(b.lines))) >= threshold*float64(len(a.lines)+len(b.lines)) &&
+				similarity(a.lines, b.lines) >= threshold
+			if a.hash == b.hash || near {
+				group[root(j)] = root(i)
+			}
+		}
+	}
+
+	var groups []duplicateGroup
+	members := map[int]int{}
+	for i, e := range entries {
+		r := root(i)
+		g, ok := members[r]
+		if !ok {
+			g = len(groups)
+			members[r] = g
+			groups = append(groups, duplicateGroup{exact: true})
+		}
+		groups[g].snippets = append(groups[g].snippets, e.snippet)
+		groups[g].exact = groups[g].exact && e.hash == entries[r].hash
+	}
+
+	var duplicates []duplicateGroup
+	for _, g := range groups {
+		if len(g.snippets) < 2 {
+			continue
+		}
+		slices.SortStableFunc(g.snippets, func(a, b Snippet) int {
+			if a.Favorite != b.Favorite {
+				if a.Favorite {
+					return -1
+				}
+				return 1
+			}
+			return compareTimes(a.Date, b.Date)
+		})
+		duplicates = append(duplicates, g)
+	}
+	return duplicates
+}
+
+// min returns the smallest of a and b.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// mergeDuplicates keeps the snippet of the group, giving it the tags of the
+// others and making it a favorite if any of them was, and moves the others to
+// the trash. It returns the updated snippets.
+func mergeDuplicates(config Config, snippets []Snippet, group []Snippet, keep Snippet) ([]Snippet, error) {
+	for _, s := range group {
+		for _, tag := range s.Tags {
+			if !slices.Contains(keep.Tags, tag) {
+				keep.Tags = append(keep.Tags, tag)
+			}
+		}
+		keep.Favorite = keep.Favorite || s.Favorite
+	}
+
+	var merged []Snippet
+	var err error
+	for _, s := range snippets {
+		switch {
+		case s.Path() == keep.Path():
+			merged = append(merged, keep)
+		case slices.ContainsFunc(group, func(other Snippet) bool { return other.Path() == s.Path() }):
+			if _, trashErr := trashSnippet(config, s); trashErr != nil {
+				err = trashErr
+				merged = append(merged, s)
+			}
+		default:
+			merged = append(merged, s)
+		}
+	}
+	return merged, err
+}
+
+// chooseKeep asks the user which snippet of the group to keep, defaulting to
+// the first one. It returns false when the group is skipped.
+func chooseKeep(group []Snippet, in *bufio.Reader, out io.Writer) (Snippet, bool) {
+	fmt.Fprintf(out, "Keep which? [1-%d, s to skip] (1) ", len(group))
+	answer, _ := in.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return group[0], true
+	}
+	i, err := strconv.Atoi(answer)
+	if err != nil || i < 1 || i > len(group) {
+		return Snippet{}, false
+	}
+	return group[i-1], true
+}
+
+// runDedupe runs the `nap dedupe` subcommand, listing the groups of duplicate
+// snippets. With -y, or when the user picks one from a terminal, a snippet of
+// each group is kept and the others are merged into it.
+func runDedupe(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	yes := flags.Bool("y", false, "keep the first snippet of each group without asking")
+	threshold := flags.Float64("threshold", defaultSimilarity, "similarity of near duplicates, from 0 to 1")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 || *threshold < 0 || *threshold > 1 {
+		fmt.Println("usage: nap dedupe [-y] [--threshold 0.9]")
+		return 2
+	}
+
+	groups := findDuplicates(config, snippets, *threshold)
+	if len(groups) == 0 {
+		fmt.Println("no duplicate snippets")
+		return 0
+	}
+	interactive := !*yes && isatty.IsTerminal(os.Stdin.Fd())
+	in := bufio.NewReader(os.Stdin)
+	merged := 0
+	for _, g := range groups {
+		kind := "near duplicates"
+		if g.exact {
+			kind = "exact duplicates"
+		}
+		fmt.Printf("%s:\n", kind)
+		for i, s := range g.snippets {
+			line := fmt.Sprintf("  %d) %s", i+1, s)
+			if s.Favorite {
+				line += " ★"
+			}
+			if len(s.Tags) > 0 {
+				line += " [" + strings.Join(s.Tags, ", ") + "]"
+			}
+			fmt.Println(line)
+		}
+
+		keep := g.snippets[0]
+		if interactive {
+			var ok bool
+			if keep, ok = chooseKeep(g.snippets, in, os.Stdout); !ok {
+				continue
+			}
+		} else if !*yes {
+			continue
+		}
+
+		var err error
+		snippets, err = mergeDuplicates(config, snippets, g.snippets, keep)
+		if err != nil {
+			fmt.Printf("could not move duplicates to the trash: %v\n", err)
+		}
+		fmt.Printf("kept %s\n", keep)
+		merged++
+	}
+	if merged > 0 {
+		writeSnippets(config, snippets)
+	} else if !interactive && !*yes {
+		fmt.Println("\nrun nap dedupe -y to keep the first snippet of each group")
+	}
+	return 0
+}
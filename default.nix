@@ -5,7 +5,7 @@ buildGoModule {
 
   src = ./.;
 
-  vendorHash = "sha256-Iz5+jZs80wWlbQ6pw0/CQHy2gaFm9pT/LdipfW9Hg4o=";
+  vendorHash = "sha256-Znf8IQSxk5rH4Kx71F+exknHljwZy7oCog/H2APRWyM=";
 
   ldflags = [
     "-s"
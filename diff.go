@@ -0,0 +1,359 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
+	"github.com/mattn/go-runewidth"
+)
+
+// diffContext is the number of unchanged lines around the changes of a
+// unified diff.
+const diffContext = 3
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+// diffLine is a line of a diff, kept, deleted from the first text or
+// inserted from the second one.
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// splitLines returns the lines of the content without their line endings.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script turning the lines a into b,
+// found with the linear space variant of Myers' algorithm, which finds the
+// middle of the script and recurses on both sides of it rather than keeping
+// every step of the search.
+func diffLines(a, b []string) []diffLine {
+	var script []diffLine
+	diffRange(&script, a, b)
+	return script
+}
+
+// diffRange appends the edit script turning the lines a into b to script,
+// after the lines they start and end with.
+func diffRange(script *[]diffLine, a, b []string) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	appendLines(script, diffEqual, a[:prefix])
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	if len(a) == 0 || len(b) == 0 {
+		appendLines(script, diffDelete, a)
+		appendLines(script, diffInsert, b)
+	} else if x, y, ok := middleSnake(a, b); ok {
+		diffRange(script, a[:x], b[:y])
+		diffRange(script, a[x:], b[y:])
+	} else {
+		// nothing in common
+		appendLines(script, diffDelete, a)
+		appendLines(script, diffInsert, b)
+	}
+	appendLines(script, diffEqual, common)
+}
+
+// appendLines appends the lines to the script with the operation.
+func appendLines(script *[]diffLine, op diffOp, lines []string) {
+	for _, line := range lines {
+		*script = append(*script, diffLine{op, line})
+	}
+}
+
+// middleSnake searches the furthest paths from the start and from the end of
+// both texts at once until they overlap, and returns where the overlap splits
+// the texts. It reports false when the texts have no line in common.
+func middleSnake(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset, size := maxD, 2*maxD+2
+	forward, backward := make([]int, size), make([]int, size)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// the paths can only overlap going forward when delta is odd
+	odd := delta%2 != 0
+	// the diagonals leaving the texts are not searched any further
+	var fStart, fEnd, bStart, bEnd int
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if i := offset + delta - k; i >= 0 && i < size && backward[i] != -1 && x >= n-backward[i] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if i := offset + delta - k; i >= 0 && i < size && forward[i] != -1 && forward[i] >= n-x {
+					fx := forward[i]
+					return fx, fx - (i - offset), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// unifiedDiff returns the unified diff between the contents, or an empty
+// string if they are the same.
+func unifiedDiff(fromName, toName, from, to string) string {
+	script := diffLines(splitLines(from), splitLines(to))
+
+	// the number of lines of each text before each line of the script
+	fromLines := make([]int, len(script)+1)
+	toLines := make([]int, len(script)+1)
+	for i, line := range script {
+		fromLines[i+1], toLines[i+1] = fromLines[i], toLines[i]
+		if line.op != diffInsert {
+			fromLines[i+1]++
+		}
+		if line.op != diffDelete {
+			toLines[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(script); {
+		if script[i].op == diffEqual {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		last := i
+		for j := i; j < len(script); j++ {
+			if script[j].op != diffEqual {
+				last = j
+			} else if j-last > 2*diffContext {
+				break
+			}
+		}
+		end := last + diffContext + 1
+		if end > len(script) {
+			end = len(script)
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(fromLines[start], fromLines[end]-fromLines[start]),
+			hunkRange(toLines[start], toLines[end]-toLines[start]))
+		for _, line := range script[start:end] {
+			b.WriteString([]string{" ", "-", "+"}[line.op] + line.text + "\n")
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange returns the range of lines of a hunk header, for count lines
+// after the first lines of the text.
+func hunkRange(first, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", first)
+	}
+	if count == 1 {
+		return fmt.Sprint(first + 1)
+	}
+	return fmt.Sprintf("%d,%d", first+1, count)
+}
+
+// colorDiff colors the headers, deleted and inserted lines of a unified diff.
+func colorDiff(style ContentBaseStyle, diff string) string {
+	lines := splitLines(diff)
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			lines[i] = style.DiffHeader.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = style.DiffHunk.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = style.Deleted.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = style.Inserted.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// sideBySide returns the lines of the contents next to each other in columns
+// fitting the width. Changed lines are marked with |, deleted ones with < and
+// inserted ones with >.
+func sideBySide(style ContentBaseStyle, from, to string, width int) string {
+	column := (width - 3) / 2
+	if column < 1 {
+		column = 1
+	}
+	cell := func(text string) string {
+		text = strings.ReplaceAll(text, "\t", strings.Repeat(" ", tabSpaces))
+		return runewidth.FillRight(runewidth.Truncate(text, column, ""), column)
+	}
+
+	var b strings.Builder
+	script := diffLines(splitLines(from), splitLines(to))
+	for i := 0; i < len(script); {
+		if script[i].op == diffEqual {
+			b.WriteString(cell(script[i].text) + "   " + cell(script[i].text) + "\n")
+			i++
+			continue
+		}
+		var deleted, inserted []string
+		for ; i < len(script) && script[i].op != diffEqual; i++ {
+			if script[i].op == diffDelete {
+				deleted = append(deleted, script[i].text)
+			} else {
+				inserted = append(inserted, script[i].text)
+			}
+		}
+		for row := 0; row < len(deleted) || row < len(inserted); row++ {
+			left, right, marker := cell(""), cell(""), " | "
+			if row < len(deleted) {
+				left = style.Deleted.Render(cell(deleted[row]))
+			} else {
+				marker = " > "
+			}
+			if row < len(inserted) {
+				right = style.Inserted.Render(cell(inserted[row]))
+			} else {
+				marker = " < "
+			}
+			b.WriteString(left + marker + right + "\n")
+		}
+	}
+	return b.String()
+}
+
+// runDiff runs the `nap diff` subcommand, printing the unified diff between
+// two snippets. Like diff, it exits with 0 when they are the same, 1 when they
+// differ and 2 on errors.
+func runDiff(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	exact := flags.Bool("exact", false, "only diff the snippets at the exact folder/name.ext")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 2 {
+		fmt.Println("usage: nap diff [--exact] <snippet> <snippet>")
+		return 2
+	}
+
+	var contents [2]string
+	var compared [2]Snippet
+	for i, arg := range args {
+		snippet, ok := lookupSnippet(arg, snippets, *exact)
+		if !ok {
+			return 2
+		}
+		content, err := readSnippet(config, snippet)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read %s: %v\n", snippet, err)
+			return 2
+		}
+		compared[i], contents[i] = snippet, string(content)
+	}
+
+	diff := unifiedDiff(compared[0].String(), compared[1].String(), contents[0], contents[1])
+	if diff == "" {
+		return 0
+	}
+	if isatty.IsTerminal(os.Stdout.Fd()) {
+		diff = colorDiff(DefaultStyles(config).Content.Focused, diff)
+	}
+	fmt.Print(diff)
+	return 1
+}
+
+// toggleCompare starts comparing the selected snippet with the others, or
+// stops comparing.
+func (m *Model) toggleCompare() tea.Cmd {
+	if m.compare != nil {
+		m.compare = nil
+		return m.updateContent()
+	}
+	s := m.selectedSnippet()
+	m.compare = &s
+	return tea.Batch(m.List().NewStatusMessage("Comparing with "+s.String()), m.updateContent())
+}
+
+// showCompare displays the snippet next to the one it is compared with in the
+// content pane.
+func (m *Model) showCompare(s Snippet) {
+	from, err := readSnippet(m.config, *m.compare)
+	if err != nil {
+		m.displayError("Unable to read " + m.compare.String())
+		return
+	}
+	to, err := readSnippet(m.config, s)
+	if err != nil {
+		m.displayError("Unable to read " + s.String())
+		return
+	}
+	m.content.setContent(sideBySide(m.ContentStyle, string(from), string(to), m.Code.Width), false)
+	m.showContent()
+}
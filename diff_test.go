@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- foo/a.txt
++++ foo/b.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := unifiedDiff("foo/a.txt", "foo/b.txt", from, to); got != want {
+		t.Logf("unexpected diff:\n%s", got)
+		t.FailNow()
+	}
+	if got := unifiedDiff("foo/a.txt", "foo/b.txt", from, from); got != "" {
+		t.Logf("expected no diff between identical snippets, got:\n%s", got)
+		t.FailNow()
+	}
+	if got := unifiedDiff("a", "b", "", "x\n"); got != "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n" {
+		t.Logf("unexpected diff from an empty snippet:\n%s", got)
+		t.FailNow()
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	got := stripANSI(sideBySide(ContentBaseStyle{}, "a\nb\nc\n", "a\nB\nc\nd\n", 11))
+	want := []string{
+		"a      a",
+		"b    | B",
+		"c      c",
+		"     > d",
+	}
+	for i, row := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if strings.TrimRight(row, " ") != want[i] {
+			t.Logf("unexpected row %d: got %q, want %q", i, row, want[i])
+			t.FailNow()
+		}
+	}
+}
+
+func TestDiffLargeSnippets(t *testing.T) {
+	const lines = 5000
+	a, b := make([]string, lines), make([]string, lines)
+	for i := range a {
+		a[i], b[i] = fmt.Sprintf("a %d", i), fmt.Sprintf("b %d", i)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	script := diffLines(a, b)
+	runtime.ReadMemStats(&after)
+	if len(script) != 2*lines || script[0].op != diffDelete || script[len(script)-1].op != diffInsert {
+		t.Logf("expected every line to be replaced, got %d lines", len(script))
+		t.FailNow()
+	}
+	// keeping every step of the search would take gigabytes
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Logf("expected the diff to take linear space, allocated %d bytes", allocated)
+		t.FailNow()
+	}
+
+	// a line in common in the middle of both
+	a[lines/2], b[lines/3] = "common", "common"
+	script = diffLines(a, b)
+	var equal []string
+	for _, line := range script {
+		if line.op == diffEqual {
+			equal = append(equal, line.text)
+		}
+	}
+	if len(script) != 2*lines-1 || len(equal) != 1 || equal[0] != "common" {
+		t.Logf("expected the line in common to be kept, got %d lines and %v", len(script), equal)
+		t.FailNow()
+	}
+}
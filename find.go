@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-isatty"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/slices"
+)
+
+// ambiguousScore is how close to the best match the other matches must score
+// for the search to be ambiguous, before their usage is counted.
+const ambiguousScore = 5
+
+// maxSuggestions is the number of snippets suggested when nothing matches.
+const maxSuggestions = 3
+
+// snippetMatch is a snippet matching a search, with the score of its fuzzy
+// match.
+type snippetMatch struct {
+	snippet Snippet
+	score   int
+}
+
+// closeMatch reports whether the fuzzy match is close enough for the snippet
+// to be printed: the search must appear as is in the folder/name.ext of the
+// snippet, ignoring case, or be made of the first letters of its words in
+// order, as in fbg for foo/bar.go. Typos like fooo for foo match other
+// snippets loosely, which are only suggested.
+func closeMatch(search string, match fuzzy.Match) bool {
+	text, search := strings.ToLower(match.Str), strings.ToLower(search)
+	if strings.Contains(text, search) {
+		return true
+	}
+	runes, rest := []rune(match.Str), []rune(search)
+	for i := 0; i < len(runes) && len(rest) > 0; i++ {
+		if wordStart(runes, i) && unicode.ToLower(runes[i]) == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// wordStart reports whether the rune at i starts a word: it begins the text,
+// follows a separator or is an upper case letter following a lower case one.
+func wordStart(runes []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, r := runes[i-1], runes[i]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsUpper(r) && unicode.IsLower(prev)
+}
+
+// matchSnippets returns the snippets matching the search closely, best first
+// with their usage counted. A snippet whose folder/name.ext or path is the
+// search is the only match, and the only possible one if exact is set. When
+// nothing matches, the closest snippets are returned as suggestions.
+func matchSnippets(search string, snippets []Snippet, usage Usage, exact bool) ([]snippetMatch, []Snippet) {
+	for _, s := range snippets {
+		if s.String() == search || s.Path() == search {
+			return []snippetMatch{{snippet: s}}, nil
+		}
+	}
+
+	var matches []snippetMatch
+	var suggestions []Snippet
+	found := fuzzy.FindFrom(search, Snippets{snippets})
+	slices.SortStableFunc(found, func(a, b fuzzy.Match) int {
+		return rank(b, snippets, usage) - rank(a, snippets, usage)
+	})
+	for _, match := range found {
+		s := snippets[match.Index]
+		if !exact && closeMatch(search, match) {
+			matches = append(matches, snippetMatch{s, match.Score})
+		} else if len(suggestions) < maxSuggestions {
+			suggestions = append(suggestions, s)
+		}
+	}
+	return matches, suggestions
+}
+
+// lookupSnippet returns the snippet the search refers to. When several
+// snippets match about as well, the user picks one if a terminal is attached.
+// Errors and suggestions are printed to stderr.
+func lookupSnippet(search string, snippets []Snippet, exact bool) (Snippet, bool) {
+	matches, suggestions := matchSnippets(search, snippets, readState().Usage, exact)
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "no snippet matches %q\n", search)
+		if len(suggestions) > 0 {
+			fmt.Fprintln(os.Stderr, "\nDid you mean:")
+			for _, s := range suggestions {
+				fmt.Fprintf(os.Stderr, "  %s\n", s)
+			}
+		}
+		return Snippet{}, false
+	}
+
+	var close []Snippet
+	for _, match := range matches {
+		if diff := matches[0].score - match.score; diff <= ambiguousScore && diff >= -ambiguousScore {
+			close = append(close, match.snippet)
+		}
+	}
+	if len(close) > 1 && isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stderr.Fd()) {
+		return chooseSnippet(close, os.Stdin, os.Stderr)
+	}
+	return matches[0].snippet, true
+}
+
+// chooseSnippet asks the user which of the snippets they meant, defaulting
+// to the first one.
+func chooseSnippet(snippets []Snippet, in io.Reader, out io.Writer) (Snippet, bool) {
+	fmt.Fprintln(out, "Several snippets match:")
+	for i, s := range snippets {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, s)
+	}
+	fmt.Fprint(out, "Which one? [1] ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return snippets[0], true
+	}
+	i, err := strconv.Atoi(answer)
+	if err != nil || i < 1 || i > len(snippets) {
+		fmt.Fprintf(out, "invalid choice %q\n", answer)
+		return Snippet{}, false
+	}
+	return snippets[i-1], true
+}
+
+// printSnippet runs `nap <snippet>`, printing the snippet to stdout, and
+// returns the exit code.
+func printSnippet(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("nap", flag.ContinueOnError)
+	exact := flags.Bool("exact", false, "only print the snippet at the exact folder/name.ext")
+	raw := flags.Bool("raw", false, "print the snippet as is, without highlighting or rendering it")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 1 {
+		fmt.Println("usage: nap [--exact] [--raw] <snippet>")
+		return 2
+	}
+	snippet, ok := lookupSnippet(args[0], snippets, *exact)
+	if !ok {
+		return 1
+	}
+	fmt.Print(snippet.Content(config, isatty.IsTerminal(os.Stdout.Fd()) && !*raw))
+	recordUsage(snippet, usagePrint)
+	return 0
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// foldersFile is the file under the nap home holding the custom order of the
+// folders.
+const foldersFile = "folders.json"
+
+// readFolderOrder returns the custom order of the folders.
+func readFolderOrder(config Config) []Folder {
+	var order []Folder
+	b, err := os.ReadFile(filepath.Join(config.Home, foldersFile))
+	if err != nil {
+		return order
+	}
+	if err := json.Unmarshal(b, &order); err != nil {
+		fmt.Printf("Unable to unmarshal %s file, %+v\n", foldersFile, err)
+	}
+	return order
+}
+
+// writeFolderOrder saves the custom order of the folders.
+func writeFolderOrder(config Config, order []Folder) error {
+	b, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(config.Home, foldersFile), b, 0o644)
+}
+
+// scanFolders returns the folders in the nap home, including empty ones.
+func scanFolders(config Config) []Folder {
+	var folders []Folder
+	entries, err := os.ReadDir(config.Home)
+	if err != nil {
+		return folders
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			folders = append(folders, Folder(entry.Name()))
+		}
+	}
+	return folders
+}
+
+// orderFolders sorts the folders following the custom order. Folders missing
+// from the order are placed after the others by name, and the virtual folders
+// come last.
+func orderFolders(order []Folder, folders []Folder) []Folder {
+	slices.SortFunc(folders, func(a, b Folder) int {
+		return compareFolders(order, a, b)
+	})
+	return folders
+}
+
+// virtualFolders is the order of the virtual folders.
+var virtualFolders = []Folder{recentFolder, frequentFolder, trashFolder}
+
+// compareFolders compares the folders following the custom order.
+func compareFolders(order []Folder, a, b Folder) int {
+	rank := func(f Folder) int {
+		if i := slices.Index(order, f); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+	if a.virtual() != b.virtual() {
+		if a.virtual() {
+			return 1
+		}
+		return -1
+	}
+	if a.virtual() {
+		return slices.Index(virtualFolders, a) - slices.Index(virtualFolders, b)
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	return strings.Compare(string(a), string(b))
+}
+
+// sortedFolders returns the folders in the order they are displayed.
+func (m *Model) sortedFolders() []Folder {
+	return orderFolders(m.folderOrder, maps.Keys(m.Lists))
+}
+
+// snippetFolders returns the folders holding snippets, without the virtual
+// folders, in the order they are displayed.
+func (m *Model) snippetFolders() []Folder {
+	return slices.DeleteFunc(m.sortedFolders(), Folder.virtual)
+}
+
+// validFolder returns an error if the name cannot be used for a new folder.
+func (m *Model) validFolder(name Folder) error {
+	if name == "" || name.virtual() || strings.ContainsRune(string(name), filepath.Separator) {
+		return fmt.Errorf("invalid folder name %q", name)
+	}
+	if _, ok := m.Lists[name]; ok {
+		return fmt.Errorf("folder %q already exists", name)
+	}
+	return nil
+}
+
+// createFolder creates a new empty folder and selects it.
+func (m *Model) createFolder(name Folder) error {
+	if err := m.validFolder(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(m.config.Home, string(name)), os.ModePerm); err != nil {
+		return err
+	}
+	m.Lists[name] = newList([]list.Item{}, m.height, m.ListStyle)
+	m.selectFolder(name)
+	return nil
+}
+
+// folderRenameOp records the rename of a folder and all of its snippets.
+type folderRenameOp struct {
+	from, to Folder
+}
+
+func (op *folderRenameOp) String() string { return "rename folder" }
+
+func (op *folderRenameOp) undo(m *Model) error {
+	return m.renameFolder(op.to, op.from)
+}
+
+func (op *folderRenameOp) redo(m *Model) error {
+	if err := m.validFolder(op.to); err != nil {
+		return err
+	}
+	return m.renameFolder(op.from, op.to)
+}
+
+// renameFolder moves the folder directory and updates every snippet in it,
+// along with its usage.
+func (m *Model) renameFolder(from, to Folder) error {
+	oldDir := filepath.Join(m.config.Home, string(from))
+	newDir := filepath.Join(m.config.Home, string(to))
+	if err := os.Rename(oldDir, newDir); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.MkdirAll(newDir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	li := m.Lists[from]
+	for i, item := range li.Items() {
+		s := item.(Snippet)
+		old := s.Path()
+		s.Folder = string(to)
+		m.usage.rename(old, s.Path())
+		li.SetItem(i, s)
+	}
+	delete(m.Lists, from)
+	m.Lists[to] = li
+	if i := slices.Index(m.folderOrder, from); i >= 0 {
+		m.folderOrder = slices.Clone(m.folderOrder)
+		m.folderOrder[i] = to
+	}
+	m.clearMarks()
+	m.updateUsageViews()
+	m.selectFolder(to)
+	return nil
+}
+
+// folderRemoveOp records the removal of a folder, either by deleting its
+// snippets or by merging them into another folder.
+type folderRemoveOp struct {
+	folder Folder
+	// into is the folder the snippets are merged into, or empty if they are
+	// deleted.
+	into Folder
+	ops  []operation
+}
+
+func (op *folderRemoveOp) String() string {
+	if op.into != "" {
+		return "merge folder"
+	}
+	return "delete folder"
+}
+
+func (op *folderRemoveOp) undo(m *Model) error {
+	if err := os.MkdirAll(filepath.Join(m.config.Home, string(op.folder)), os.ModePerm); err != nil {
+		return err
+	}
+	m.Lists[op.folder] = newList([]list.Item{}, m.height, m.ListStyle)
+	if err := (&batchOp{ops: op.ops}).undo(m); err != nil {
+		return err
+	}
+	m.selectFolder(op.folder)
+	return nil
+}
+
+func (op *folderRemoveOp) redo(m *Model) error {
+	if op.ops == nil {
+		for _, item := range slices.Clone(m.Lists[op.folder].Items()) {
+			s := item.(Snippet)
+			var o operation = &deleteOp{snippet: s}
+			if op.into != "" {
+				to := s
+				to.Folder = string(op.into)
+				o = &renameOp{from: s, to: m.uniqueSnippet(to)}
+			}
+			if err := o.redo(m); err != nil {
+				return err
+			}
+			op.ops = append(op.ops, o)
+		}
+	} else if err := (&batchOp{ops: op.ops}).redo(m); err != nil {
+		return err
+	}
+
+	_ = os.Remove(filepath.Join(m.config.Home, string(op.folder)))
+	delete(m.Lists, op.folder)
+	m.clearMarks()
+	selected := op.into
+	if selected == "" {
+		selected = m.snippetFolders()[0]
+	}
+	m.selectFolder(selected)
+	return nil
+}
+
+// deleteFolder moves all snippets of the folder to the trash and removes it.
+func (m *Model) deleteFolder(folder Folder) error {
+	if folder.virtual() {
+		return fmt.Errorf("cannot delete %s", folder.Title())
+	}
+	if len(m.snippetFolders()) <= 1 {
+		return fmt.Errorf("cannot delete the last folder")
+	}
+	return m.do(&folderRemoveOp{folder: folder})
+}
+
+// mergeFolder moves all snippets of the folder into another one and removes
+// it. Snippets whose name is taken in the other folder get a numeric suffix.
+func (m *Model) mergeFolder(folder, into Folder) error {
+	if folder.virtual() || into.virtual() || folder == into {
+		return fmt.Errorf("cannot merge %s into %s", folder.Title(), into.Title())
+	}
+	if _, ok := m.Lists[into]; !ok {
+		return fmt.Errorf("folder %q does not exist", into)
+	}
+	return m.do(&folderRemoveOp{folder: folder, into: into})
+}
+
+// folderMoveOp records a folder being moved in the custom folder order.
+type folderMoveOp struct {
+	before, after []Folder
+}
+
+func (op *folderMoveOp) String() string { return "move folder" }
+
+func (op *folderMoveOp) undo(m *Model) error {
+	m.folderOrder = op.before
+	m.selectFolder(m.selectedFolder())
+	return nil
+}
+
+func (op *folderMoveOp) redo(m *Model) error {
+	m.folderOrder = op.after
+	m.selectFolder(m.selectedFolder())
+	return nil
+}
+
+// moveFolder moves the selected folder up or down by delta in the custom
+// folder order.
+func (m *Model) moveFolder(delta int) {
+	order := m.snippetFolders()
+	from := slices.Index(order, m.selectedFolder())
+	to := from + delta
+	if from < 0 || to < 0 || to >= len(order) {
+		return
+	}
+	after := slices.Clone(order)
+	after[from], after[to] = after[to], after[from]
+	_ = m.do(&folderMoveOp{before: m.folderOrder, after: after})
+}
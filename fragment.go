@@ -0,0 +1,323 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/exp/slices"
+)
+
+// fragmentHeader introduces each file of a multi-file snippet printed or
+// copied as a whole, like head does.
+const fragmentHeader = "==> %s <==\n"
+
+// fragments returns the relative paths of the files of a multi-file snippet,
+// in lexical order, leaving out hidden and ignored files.
+func fragments(config Config, s Snippet) ([]string, error) {
+	dir := filepath.Join(config.Home, s.Path())
+	ignore := readIgnore(config)
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(config.Home, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") || ignore.ignored(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return names, err
+}
+
+// readFragment returns the content of the named file of a multi-file
+// snippet.
+func readFragment(config Config, s Snippet, name string) ([]byte, error) {
+	names, err := fragments(config, s)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(names, name) {
+		return nil, fmt.Errorf("%s has no file %s", s, name)
+	}
+	return os.ReadFile(filepath.Join(config.Home, s.Path(), filepath.FromSlash(name)))
+}
+
+// fragmentLanguage returns the language highlighting a fragment, its
+// extension or else its name, which recognizes files like Dockerfile.
+func fragmentLanguage(name string) string {
+	if ext := filepath.Ext(name); ext != "" {
+		return strings.TrimPrefix(ext, ".")
+	}
+	return filepath.Base(name)
+}
+
+// joinFragments returns the files of a multi-file snippet one after the
+// other, each after a header naming it, highlighted when highlight is set.
+func joinFragments(config Config, s Snippet, highlight bool) (string, error) {
+	names, err := fragments(config, s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, name := range names {
+		content, err := os.ReadFile(filepath.Join(config.Home, s.Path(), filepath.FromSlash(name)))
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, fragmentHeader, name)
+		text := string(content)
+		if highlight {
+			text = highlightContent(config, text, fragmentLanguage(name))
+		}
+		b.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// readSnippet returns the content of the snippet, the files of a multi-file
+// snippet being joined.
+func readSnippet(config Config, s Snippet) ([]byte, error) {
+	if !s.Multi {
+		return os.ReadFile(filepath.Join(config.Home, s.Path()))
+	}
+	content, err := joinFragments(config, s, false)
+	return []byte(content), err
+}
+
+// snippetFile is a file written out of a snippet, named by its path
+// relative to where it is written.
+type snippetFile struct {
+	name    string
+	content []byte
+	mode    fs.FileMode
+}
+
+// snippetFiles returns the files of the snippet: the snippet file, or the
+// files of a multi-file snippet with their relative paths.
+func snippetFiles(config Config, s Snippet) ([]snippetFile, error) {
+	src := filepath.Join(config.Home, s.Folder)
+	names := []string{s.File}
+	if s.Multi {
+		var err error
+		if names, err = fragments(config, s); err != nil {
+			return nil, err
+		}
+		src = filepath.Join(config.Home, s.Path())
+	}
+
+	var files []snippetFile
+	for _, name := range names {
+		file := filepath.Join(src, filepath.FromSlash(name))
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, snippetFile{name, content, info.Mode().Perm()})
+	}
+	return files, nil
+}
+
+// writeFiles writes the files to dir and returns their paths. Nothing is
+// written if a file exists unless force is set, or if a file would leave dir,
+// such as a name given ../ by a placeholder.
+func writeFiles(dir string, files []snippetFile, force bool) ([]string, error) {
+	var paths []string
+	for _, f := range files {
+		name := filepath.Clean(filepath.FromSlash(f.name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s would be written outside %s", f.name, dir)
+		}
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil && !force {
+			return nil, fmt.Errorf("%s already exists, use --force to overwrite it", path)
+		}
+		paths = append(paths, path)
+	}
+	for i, f := range files {
+		if err := os.MkdirAll(filepath.Dir(paths[i]), os.ModePerm); err != nil {
+			return nil, err
+		}
+		// keep the mode so that scripts stay executable
+		if err := os.WriteFile(paths[i], f.content, f.mode); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// materializeSnippet writes the files of the snippet to dir, the files of a
+// multi-file snippet keeping their relative paths, and returns the paths
+// written. Nothing is written if a file exists unless force is set.
+func materializeSnippet(config Config, s Snippet, dir string, force bool) ([]string, error) {
+	files, err := snippetFiles(config, s)
+	if err != nil {
+		return nil, err
+	}
+	return writeFiles(dir, files, force)
+}
+
+// runMaterialize runs the `nap materialize` subcommand, writing the files of
+// a snippet to a directory, and returns the exit code.
+func runMaterialize(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("materialize", flag.ContinueOnError)
+	exact := flags.Bool("exact", false, "only write the snippet at the exact folder/name.ext")
+	force := flags.Bool("force", false, "overwrite existing files")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 2 {
+		fmt.Println("usage: nap materialize [--exact] [--force] <snippet> <dir>")
+		return 2
+	}
+	snippet, ok := lookupSnippet(args[0], snippets, *exact)
+	if !ok {
+		return 1
+	}
+	paths, err := materializeSnippet(config, snippet, args[1], *force)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, path := range paths {
+		fmt.Printf("wrote %s\n", path)
+	}
+	recordUsage(snippet, usagePrint)
+	return 0
+}
+
+// fragmentTabs are the files of the multi-file snippet displayed in the
+// content pane, one at a time.
+type fragmentTabs struct {
+	// snippet is the path of the snippet the files belong to.
+	snippet string
+	names   []string
+	active  int
+}
+
+// activeName returns the name of the file displayed.
+func (f fragmentTabs) activeName() string {
+	if f.active >= len(f.names) {
+		return ""
+	}
+	return f.names[f.active]
+}
+
+// showFragment displays the active file of the multi-file snippet in the
+// content pane, listing the files of the snippet as tabs, and returns the Cmd
+// highlighting it.
+func (m *Model) showFragment(s Snippet) tea.Cmd {
+	names, err := fragments(m.config, s)
+	if err != nil || len(names) == 0 {
+		m.fragments = fragmentTabs{}
+		m.displayKeyHint(m.noContentHints())
+		return nil
+	}
+	active := 0
+	if m.fragments.snippet == s.Path() {
+		active = slices.Index(names, m.fragments.activeName())
+		if active < 0 {
+			active = 0
+		}
+	}
+	m.fragments = fragmentTabs{snippet: s.Path(), names: names, active: active}
+
+	name := m.fragments.activeName()
+	file := filepath.Join(m.config.Home, s.Path(), filepath.FromSlash(name))
+	info, err := os.Stat(file)
+	if err != nil {
+		m.displayError("Unable to read " + name)
+		return nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		m.displayError("Unable to read " + name)
+		return nil
+	}
+	if len(content) == 0 {
+		m.displayKeyHint(m.noContentHints())
+		return nil
+	}
+	if isBinary(string(content)) {
+		m.displayError(fmt.Sprintf("Binary attachment, %d bytes.", len(content)))
+		return nil
+	}
+	return m.showHighlighted(file, info, string(content), fragmentLanguage(name))
+}
+
+// switchFragment displays the next or previous file of the multi-file
+// snippet.
+func (m *Model) switchFragment(delta int) tea.Cmd {
+	n := len(m.fragments.names)
+	if n == 0 {
+		return nil
+	}
+	m.fragments.active = (m.fragments.active + delta + n) % n
+	return m.updateContent()
+}
+
+// copyFragment copies the file of the multi-file snippet displayed to the
+// clipboard.
+func (m *Model) copyFragment() tea.Cmd {
+	s := m.selectedSnippet()
+	if m.fragments.snippet != s.Path() {
+		return m.copySnippets([]Snippet{s})
+	}
+	name := m.fragments.activeName()
+	content, err := readFragment(m.config, s, name)
+	if err != nil {
+		m.displayError("Unable to read " + name)
+		return nil
+	}
+	m.recordUsage([]Snippet{s}, usageCopy)
+	clipboard.WriteAll(string(content))
+	return m.List().NewStatusMessage("Copied " + name)
+}
+
+// fragmentTabsView returns the name of the multi-file snippet followed by the
+// names of its files, the active one highlighted.
+func (m *Model) fragmentTabsView(s Snippet) string {
+	tabs := []string{m.ContentStyle.Title.Render(s.Name)}
+	for i, name := range m.fragments.names {
+		if i == m.fragments.active {
+			tabs = append(tabs, m.ContentStyle.Title.Render(name))
+		} else {
+			tabs = append(tabs, m.ContentStyle.Tab.Render(name))
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Left, tabs...)
+}
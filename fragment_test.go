@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
+)
+
+// writeMultiSnippet creates the docker/stack multi-file snippet.
+func writeMultiSnippet(t *testing.T, home string) {
+	t.Helper()
+
+	files := map[string]string{
+		"Dockerfile":         "FROM alpine\n",
+		"compose.yaml":       "services: {}\n",
+		"scripts/run.sh":     "#!/bin/sh\necho run\n",
+		".hidden/ignored.sh": "ignored\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(home, "docker", "stack", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Logf("could not create directory: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Logf("could not create file: %v", err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestFragments(t *testing.T) {
+	tmp := tmpHome(t)
+	writeMultiSnippet(t, tmp)
+	cfg := readConfig()
+	snippets := scanSnippets(cfg, nil)
+	i := slices.IndexFunc(snippets, func(s Snippet) bool { return s.Multi })
+	if i < 0 || snippets[i].String() != "docker/stack" {
+		t.Logf("directory should be scanned as a multi-file snippet: got %+v", snippets)
+		t.FailNow()
+	}
+	s := snippets[i]
+
+	names, err := fragments(cfg, s)
+	if err != nil || !slices.Equal(names, []string{"Dockerfile", "compose.yaml", "scripts/run.sh"}) {
+		t.Logf("unexpected files: got %v, %v", names, err)
+		t.FailNow()
+	}
+	if _, err := readFragment(cfg, s, "../stack/Dockerfile"); err == nil {
+		t.Log("only the files of the snippet should be read")
+		t.FailNow()
+	}
+	want := "==> Dockerfile <==\nFROM alpine\n\n==> compose.yaml <==\nservices: {}\n\n==> scripts/run.sh <==\n#!/bin/sh\necho run\n"
+	if got := s.Content(cfg, false); got != want {
+		t.Logf("expected the files joined:\n%s\ngot:\n%s", want, got)
+		t.FailNow()
+	}
+
+	dir := t.TempDir()
+	paths, err := materializeSnippet(cfg, s, dir, false)
+	if err != nil || len(paths) != 3 {
+		t.Logf("could not materialize snippet: %v, %v", paths, err)
+		t.FailNow()
+	}
+	info, err := os.Stat(filepath.Join(dir, "scripts", "run.sh"))
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Logf("files should keep their relative paths and mode: %v", err)
+		t.FailNow()
+	}
+	if _, err := materializeSnippet(cfg, s, dir, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Logf("existing files should not be overwritten: got %v", err)
+		t.FailNow()
+	}
+	if _, err := materializeSnippet(cfg, s, dir, true); err != nil {
+		t.Logf("existing files should be overwritten with force: got %v", err)
+		t.FailNow()
+	}
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI([]string{"show", "docker/stack", "--file", "compose.yaml"}) })
+	if code != 0 || out != "services: {}\n" {
+		t.Logf("expected the file to be printed: exit %d, got %q", code, out)
+		t.FailNow()
+	}
+	captureStdout(t, func() { code = runCLI([]string{"materialize", "docker/stack", filepath.Join(dir, "copy")}) })
+	if _, err := os.Stat(filepath.Join(dir, "copy", "compose.yaml")); code != 0 || err != nil {
+		t.Logf("expected the files to be written: exit %d, %v", code, err)
+		t.FailNow()
+	}
+}
+
+func TestFragmentTabs(t *testing.T) {
+	tmp := tmpHome(t)
+	writeMultiSnippet(t, tmp)
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	if !m.selectedSnippet().Multi {
+		t.Logf("expected the multi-file snippet to be selected, got %s", m.selectedSnippet())
+		t.FailNow()
+	}
+	m.updateContentView(updateContentMsg(m.selectedSnippet()))
+	if got := m.fragments.activeName(); got != "Dockerfile" || !strings.Contains(strings.Join(m.content.text, "\n"), "alpine") {
+		t.Logf("expected the first file to be shown, got %q", got)
+		t.FailNow()
+	}
+
+	m.updateKeyMap()
+	if !m.keys.NextFragment.Enabled() || m.keys.CopySnippet.Help().Desc != "copy file" {
+		t.Log("expected the file keys to be enabled")
+		t.FailNow()
+	}
+	if m.keys.PasteSnippet.Enabled() {
+		t.Log("expected pasting to be disabled for multi-file snippets")
+		t.FailNow()
+	}
+	m.switchFragment(-1)
+	m.updateContentView(updateContentMsg(m.selectedSnippet()))
+	if got := m.fragments.activeName(); got != "scripts/run.sh" || !strings.Contains(strings.Join(m.content.text, "\n"), "echo run") {
+		t.Logf("expected the last file to be shown, got %q", got)
+		t.FailNow()
+	}
+	if got := m.selectedSnippetFilePath(); got != filepath.Join(tmp, "docker", "stack", "scripts", "run.sh") {
+		t.Logf("expected the shown file to be edited, got %s", got)
+		t.FailNow()
+	}
+	if got, err := m.contentLines(1, 1); err != nil || got != "echo run\n" {
+		t.Logf("expected the line of the shown file to be copied, got %q: %v", got, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// highlightCacheSize is how many highlighted files are kept, which is plenty
+// for going back and forth between the snippets of a few folders.
+const highlightCacheSize = 256
+
+// highlightKey identifies a highlighted file: a change to the file, the theme
+// or, for rendered Markdown, the width of the pane highlights it again.
+type highlightKey struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	theme    string
+	language string
+	// render is set when the file is rendered as Markdown, wrapped to width,
+	// rather than highlighted as code.
+	render bool
+	width  int
+}
+
+// highlightCache holds the files highlighted in the content pane, so that
+// going back to a snippet shows it at once.
+type highlightCache struct {
+	mu      sync.Mutex
+	entries map[highlightKey]string
+}
+
+// highlighted holds the files highlighted during the session.
+var highlighted = &highlightCache{entries: map[highlightKey]string{}}
+
+// get returns the highlighted file, if it was highlighted before.
+func (c *highlightCache) get(key highlightKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.entries[key]
+	return s, ok
+}
+
+// put records the highlighted file, dropping the others once the cache is
+// full.
+func (c *highlightCache) put(key highlightKey, s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= highlightCacheSize {
+		c.entries = map[highlightKey]string{}
+	}
+	c.entries[key] = s
+}
+
+// highlightFile returns the content of the file highlighted as key says,
+// from the cache when it was highlighted before.
+func highlightFile(config Config, key highlightKey, content string) (string, error) {
+	if s, ok := highlighted.get(key); ok {
+		return s, nil
+	}
+	var s string
+	if key.render {
+		rendered, err := renderMarkdown(config, content, key.width)
+		if err != nil {
+			return "", err
+		}
+		s = rendered
+	} else {
+		var b bytes.Buffer
+		if err := quick.Highlight(&b, content, key.language, "terminal16m", key.theme); err != nil {
+			return "", err
+		}
+		s = b.String()
+	}
+	highlighted.put(key, s)
+	return s, nil
+}
+
+// highlightedMsg carries a file highlighted in the background.
+type highlightedMsg struct {
+	key     highlightKey
+	content string
+	err     error
+}
+
+// showHighlighted displays the content of the file in the content pane. It
+// is highlighted at once when it was before; otherwise it is shown as is and
+// the returned Cmd highlights it in the background, so that scrolling through
+// the snippets quickly is not held up by the snippets passed by.
+func (m *Model) showHighlighted(path string, info os.FileInfo, content, language string) tea.Cmd {
+	key := highlightKey{
+		path:     path,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		theme:    m.config.Theme,
+		language: language,
+	}
+	if isMarkdown(language) && !m.raw {
+		key.render, key.width = true, m.Code.Width
+	}
+	m.highlighting = key
+
+	if s, ok := highlighted.get(key); ok {
+		m.content.setContent(s, !key.render)
+		m.showContent()
+		return nil
+	}
+	m.content.setContent(content, !key.render)
+	m.showContent()
+	config := m.config
+	return func() tea.Msg {
+		s, err := highlightFile(config, key, content)
+		return highlightedMsg{key, s, err}
+	}
+}
+
+// showHighlightedMsg displays the file highlighted in the background, unless
+// another file or the same file changed has been shown since.
+func (m *Model) showHighlightedMsg(msg highlightedMsg) {
+	if msg.key != m.highlighting || m.run != nil || m.compare != nil {
+		return
+	}
+	if msg.err != nil {
+		if msg.key.render {
+			m.displayError("Unable to render file.")
+		} else {
+			m.displayError("Unable to highlight file.")
+		}
+		return
+	}
+	m.content.setContent(msg.content, !msg.key.render)
+	m.showContent()
+}
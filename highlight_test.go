@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHighlightInBackground(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "misc"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	file := filepath.Join(tmp, "misc", "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+
+	_, cmd := m.updateContentView(updateContentMsg(m.selectedSnippet()))
+	if cmd == nil || m.content.lines[0] != "package main" {
+		t.Logf("expected the content shown as is while highlighting it, got %q", m.content.lines)
+		t.FailNow()
+	}
+	msg := cmd()
+	m.Update(msg)
+	if !strings.Contains(m.content.lines[0], "\x1b[") || m.content.text[0] != "package main" {
+		t.Logf("expected the content highlighted, got %q", m.content.lines)
+		t.FailNow()
+	}
+
+	if _, cmd := m.updateContentView(updateContentMsg(m.selectedSnippet())); cmd != nil || !strings.Contains(m.content.lines[0], "\x1b[") {
+		t.Log("expected the content highlighted before to be shown at once")
+		t.FailNow()
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(file, []byte("package other\n"), 0o644); err != nil {
+		t.Logf("could not update snippet: %v", err)
+		t.FailNow()
+	}
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Logf("could not update snippet time: %v", err)
+		t.FailNow()
+	}
+	if _, cmd := m.updateContentView(updateContentMsg(m.selectedSnippet())); cmd == nil || m.content.text[0] != "package other" {
+		t.Logf("expected the changed content to be highlighted again, got %q", m.content.text)
+		t.FailNow()
+	}
+	m.Update(msg)
+	if m.content.text[0] != "package other" {
+		t.Logf("the content highlighted for the previous file should be ignored, got %q", m.content.text)
+		t.FailNow()
+	}
+}
+
+func BenchmarkUpdateContent(b *testing.B) {
+	cfg := benchHome(b, 5000)
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	items := m.List().Items()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// scrolling through the snippets only highlights the one stopped on
+		m.updateContentView(updateContentMsg(items[i%len(items)].(Snippet)))
+	}
+}
+
+func BenchmarkHighlightFile(b *testing.B) {
+	cfg := benchHome(b, 0)
+	content := strings.Repeat("func main() {\n\tfmt.Println(\"hello\")\n}\n", 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// a new key each time so that nothing comes from the cache
+		key := highlightKey{path: "main.go", size: int64(i), theme: cfg.Theme, language: "go"}
+		if _, err := highlightFile(cfg, key, content); err != nil {
+			b.Logf("could not highlight: %v", err)
+			b.FailNow()
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// operation is a change made from the TUI that can be undone and redone.
+type operation interface {
+	// undo reverts the operation.
+	undo(m *Model) error
+	// redo applies the operation again after it has been undone.
+	redo(m *Model) error
+	// String describes the operation to the user.
+	String() string
+}
+
+// history holds the operations performed during the session.
+type history struct {
+	undone []operation
+	done   []operation
+}
+
+// push records a newly performed operation, discarding anything that could
+// have been redone.
+func (h *history) push(op operation) {
+	h.done = append(h.done, op)
+	h.undone = nil
+}
+
+// do performs a new operation and records it in the history.
+func (m *Model) do(op operation) error {
+	if err := op.redo(m); err != nil {
+		return err
+	}
+	m.history.push(op)
+	m.updateUsageViews()
+	return nil
+}
+
+// undo reverts the last operation and returns a Cmd to refresh the views.
+func (m *Model) undo() tea.Cmd {
+	if len(m.history.done) == 0 {
+		return nil
+	}
+	op := m.history.done[len(m.history.done)-1]
+	m.history.done = m.history.done[:len(m.history.done)-1]
+	if err := op.undo(m); err != nil {
+		m.displayError(fmt.Sprintf("Unable to undo %s: %v", op, err))
+		return nil
+	}
+	m.history.undone = append(m.history.undone, op)
+	return tea.Batch(m.List().NewStatusMessage("Undid "+op.String()), m.refresh())
+}
+
+// redo applies the last undone operation again and returns a Cmd to refresh
+// the views.
+func (m *Model) redo() tea.Cmd {
+	if len(m.history.undone) == 0 {
+		return nil
+	}
+	op := m.history.undone[len(m.history.undone)-1]
+	m.history.undone = m.history.undone[:len(m.history.undone)-1]
+	if err := op.redo(m); err != nil {
+		m.displayError(fmt.Sprintf("Unable to redo %s: %v", op, err))
+		return nil
+	}
+	m.history.done = append(m.history.done, op)
+	return tea.Batch(m.List().NewStatusMessage("Redid "+op.String()), m.refresh())
+}
+
+// refresh returns a Cmd that updates the content view after the lists were
+// modified.
+func (m *Model) refresh() tea.Cmd {
+	m.updateUsageViews()
+	return m.updateContent()
+}
+
+func (op *renameOp) String() string { return "rename" }
+
+func (op *renameOp) undo(m *Model) error {
+	oldPath := filepath.Join(m.config.Home, op.from.Path())
+	newPath := filepath.Join(m.config.Home, op.to.Path())
+	if oldPath != newPath {
+		if err := os.Rename(newPath, oldPath); err != nil {
+			return err
+		}
+	}
+	m.replaceSnippet(op.to, op.from)
+	if op.from.Folder != op.to.Folder {
+		// put the snippet back where it was rather than at the top
+		m.removeSnippet(op.from.Path())
+		m.insertSnippet(op.index, op.from)
+	}
+
+	if op.overwrite && op.overwrittenFile {
+		if err := os.WriteFile(newPath, op.overwrittenContent, 0o644); err != nil {
+			return err
+		}
+	}
+	if op.overwritten != nil {
+		m.insertSnippet(op.overwrittenIndex, *op.overwritten)
+	}
+	m.selectSnippet(op.from)
+	return nil
+}
+
+func (op *renameOp) redo(m *Model) error {
+	if err := m.renameSnippet(op, op.overwrite); err != nil {
+		return err
+	}
+	m.selectSnippet(op.to)
+	return nil
+}
+
+// deleteOp records the deletion of a snippet, which is kept in the trash so
+// that it can be restored.
+type deleteOp struct {
+	snippet Snippet
+	index   int
+	trashed TrashedSnippet
+}
+
+func (op *deleteOp) String() string { return "delete" }
+
+func (op *deleteOp) undo(m *Model) error {
+	s, err := restoreTrashed(m.config, op.trashed, m.pathTaken)
+	if err != nil {
+		return err
+	}
+	m.removeSnippet(op.trashed.item().Path())
+	op.snippet = s
+	m.insertSnippet(op.index, s)
+	m.selectSnippet(s)
+	return nil
+}
+
+func (op *deleteOp) redo(m *Model) error {
+	t, err := trashSnippet(m.config, op.snippet)
+	if err != nil {
+		return err
+	}
+	op.trashed = t
+	m.removeSnippet(op.snippet.Path())
+	m.insertSnippet(0, t.item())
+	return nil
+}
+
+// deleteSnippet moves the snippet to the trash.
+func (m *Model) deleteSnippet(s Snippet) error {
+	_, idx, _ := m.locateSnippet(s.Path())
+	return m.do(&deleteOp{snippet: s, index: idx})
+}
+
+// purgeSnippet permanently deletes the snippet from the trash.
+func (m *Model) purgeSnippet(s Snippet) error {
+	t, ok := findTrashed(m.config, s.File)
+	if ok {
+		if err := purgeTrashed(m.config, t); err != nil {
+			return err
+		}
+	}
+	m.removeSnippet(s.Path())
+	return nil
+}
+
+// restoreSnippet moves the snippet from the trash back to its folder.
+func (m *Model) restoreSnippet(s Snippet) (Snippet, error) {
+	t, ok := findTrashed(m.config, s.File)
+	if !ok {
+		return s, fmt.Errorf("%s is not in the trash", s)
+	}
+	restored, err := restoreTrashed(m.config, t, m.pathTaken)
+	if err != nil {
+		return s, err
+	}
+	m.removeSnippet(s.Path())
+	m.insertSnippet(0, restored)
+	return restored, nil
+}
+
+// moveOp records a snippet being moved within its folder's list. The snippet
+// is found by its path, since the list may have changed since.
+type moveOp struct {
+	folder   Folder
+	snippet  string
+	from, to int
+}
+
+func (op *moveOp) String() string { return "move" }
+
+func (op *moveOp) undo(m *Model) error {
+	return m.moveItem(op.folder, op.snippet, op.from)
+}
+
+func (op *moveOp) redo(m *Model) error {
+	return m.moveItem(op.folder, op.snippet, op.to)
+}
+
+// moveSnippet moves the selected snippet to the given index of its list.
+func (m *Model) moveSnippet(to int) {
+	from := m.List().Index()
+	if to < 0 || to >= len(m.List().Items()) || from == to {
+		return
+	}
+	op := &moveOp{folder: m.selectedFolder(), snippet: m.selectedSnippet().Path(), from: from, to: to}
+	_ = m.do(op)
+}
+
+// moveItem moves the snippet at path to index to of the folder's list and
+// selects it. It fails when the snippet left the folder or the index is past
+// the end of the list.
+func (m *Model) moveItem(folder Folder, path string, to int) error {
+	f, from, ok := m.locateSnippet(path)
+	if !ok || f != folder {
+		return fmt.Errorf("%s is no longer in %s", path, folder)
+	}
+	li := m.Lists[folder]
+	if to < 0 || to >= len(li.Items()) {
+		return fmt.Errorf("%s has fewer snippets than before", folder)
+	}
+	item := li.Items()[from]
+	li.RemoveItem(from)
+	li.InsertItem(to, item)
+	li.Select(to)
+	renumber(li)
+	return nil
+}
+
+// pasteOp records content appended to a snippet from the clipboard.
+type pasteOp struct {
+	snippet Snippet
+	// size is the size of the snippet file before the paste.
+	size    int64
+	content string
+}
+
+func (op *pasteOp) String() string { return "paste" }
+
+func (op *pasteOp) undo(m *Model) error {
+	m.selectSnippet(op.snippet)
+	if err := os.Truncate(filepath.Join(m.config.Home, op.snippet.Path()), op.size); err != nil {
+		return err
+	}
+	m.rehashSnippet(op.snippet.Path())
+	return nil
+}
+
+func (op *pasteOp) redo(m *Model) error {
+	m.selectSnippet(op.snippet)
+	if err := appendFile(filepath.Join(m.config.Home, op.snippet.Path()), op.content); err != nil {
+		return err
+	}
+	m.rehashSnippet(op.snippet.Path())
+	return nil
+}
+
+// pasteSnippet appends the content to the snippet file.
+func (m *Model) pasteSnippet(s Snippet, content string) error {
+	path := filepath.Join(m.config.Home, s.Path())
+	var size int64
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	}
+	return m.do(&pasteOp{snippet: s, size: size, content: content})
+}
+
+// appendFile appends the content to the file, creating it if necessary.
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
+
+// batchOp records several operations performed at once, such as a bulk
+// action on the marked snippets, so that they are undone together.
+type batchOp struct {
+	name string
+	ops  []operation
+}
+
+func (op *batchOp) String() string { return op.name }
+
+func (op *batchOp) undo(m *Model) error {
+	for i := len(op.ops) - 1; i >= 0; i-- {
+		if err := op.ops[i].undo(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (op *batchOp) redo(m *Model) error {
+	for _, o := range op.ops {
+		if err := o.redo(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataOp records changes to the metadata of snippets, such as their tags,
+// which leave the snippet files untouched.
+type metadataOp struct {
+	name          string
+	before, after []Snippet
+}
+
+func (op *metadataOp) String() string { return op.name }
+
+func (op *metadataOp) undo(m *Model) error {
+	for i := range op.after {
+		m.replaceSnippet(op.after[i], op.before[i])
+	}
+	return nil
+}
+
+func (op *metadataOp) redo(m *Model) error {
+	for i := range op.before {
+		m.replaceSnippet(op.before[i], op.after[i])
+	}
+	return nil
+}
+
+// selectSnippet selects the folder and list item of the given snippet.
+func (m *Model) selectSnippet(s Snippet) {
+	folder, idx, ok := m.locateSnippet(s.Path())
+	if !ok {
+		return
+	}
+	m.Lists[folder].Select(idx)
+	m.selectFolder(folder)
+}
+
+// selectFolder selects the given folder in the folders list, adding it to the
+// list if it is new.
+func (m *Model) selectFolder(folder Folder) {
+	msg := m.updateFoldersView(folder).(updateFoldersMsg)
+	m.Folders.SetItems(msg.items)
+	m.Folders.Select(msg.selectedFolderIndex)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// napignoreFile is the file of the home directory listing the files that are
+// not snippets, in the gitignore syntax.
+const napignoreFile = ".napignore"
+
+// defaultIgnore are the files ignored unless the config says otherwise:
+// editor swap and backup files, and the files left by macOS and Windows.
+var defaultIgnore = []string{"*~", "*.swp", "*.swo", "#*#", ".DS_Store", "Thumbs.db"}
+
+// ignoreRule is a line of .napignore or an ignore glob of the config.
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	// negate is set for the rules starting with !, which include again what
+	// the rules before them ignore.
+	negate bool
+	// dirOnly is set for the rules ending with /, which only match
+	// directories.
+	dirOnly bool
+}
+
+// ignoreRules are the rules telling which files are not snippets. The last
+// rule matching a file decides.
+type ignoreRules []ignoreRule
+
+// parseIgnore returns the rules of the lines, in the gitignore syntax: blank
+// lines and lines starting with # are left out, ! negates the rule, a trailing
+// / only matches directories and a pattern holding a / is relative to the
+// root rather than matching at any depth. * and ? do not match /, while **
+// matches any number of directories.
+func parseIgnore(lines []string) ignoreRules {
+	var rules ignoreRules
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globRegexp(line)
+		if anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "(^|/)" + expr + "$"
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globRegexp returns the regular expression matching the paths matched by the
+// glob.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// readIgnore returns the ignore globs of the config followed by the rules of
+// the .napignore file of the home directory, which may override them.
+func readIgnore(config Config) ignoreRules {
+	lines := slices.Clone(config.Ignore)
+	fi, err := os.Open(filepath.Join(config.Home, napignoreFile))
+	if err != nil {
+		return parseIgnore(lines)
+	}
+	defer fi.Close()
+	scanner := bufio.NewScanner(fi)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return parseIgnore(lines)
+}
+
+// ignored reports whether the file or directory at the slash separated path,
+// relative to where the rules apply, is ignored, which it is as well when one
+// of the directories holding it is.
+func (r ignoreRules) ignored(path string, isDir bool) bool {
+	if len(r) == 0 {
+		return false
+	}
+	path = strings.Trim(path, "/")
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.match(path, isDir)
+}
+
+// match reports whether the last rule matching the path ignores it, without
+// looking at the directories holding it.
+func (r ignoreRules) match(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnore([]string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/misc/secret.txt",
+		"docs/**/draft.md",
+		"tmp[0-9]",
+		`\#notes`,
+	})
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"misc/app.log", false, true},
+		{"misc/keep.log", false, false},
+		{"misc/build", true, true},
+		{"misc/build", false, false},
+		{"go/build/main.go", false, true},
+		{"misc/secret.txt", false, true},
+		{"other/misc/secret.txt", false, false},
+		{"docs/draft.md", false, true},
+		{"docs/a/b/draft.md", false, true},
+		{"misc/tmp1", false, true},
+		{"misc/tmpx", false, false},
+		{"misc/#notes", false, true},
+		{"misc/main.go", false, false},
+	}
+	for _, test := range tests {
+		if got := rules.ignored(test.path, test.isDir); got != test.ignored {
+			t.Logf("ignored(%s, %v) = %v, want %v", test.path, test.isDir, got, test.ignored)
+			t.FailNow()
+		}
+	}
+}
@@ -4,46 +4,114 @@ import "github.com/charmbracelet/bubbles/key"
 
 // KeyMap is the mappings of actions to key bindings.
 type KeyMap struct {
-	Quit            key.Binding
-	Search          key.Binding
-	ToggleHelp      key.Binding
-	NewSnippet      key.Binding
-	MoveSnippetUp   key.Binding
-	MoveSnippetDown key.Binding
-	DeleteSnippet   key.Binding
-	EditSnippet     key.Binding
-	CopySnippet     key.Binding
-	PasteSnippet    key.Binding
-	SetFolder       key.Binding
-	RenameSnippet   key.Binding
-	TagSnippet      key.Binding
-	Confirm         key.Binding
-	Cancel          key.Binding
-	NextPane        key.Binding
-	PreviousPane    key.Binding
-	ChangeFolder    key.Binding
+	Quit             key.Binding
+	Search           key.Binding
+	ToggleHelp       key.Binding
+	NewSnippet       key.Binding
+	MoveSnippetUp    key.Binding
+	MoveSnippetDown  key.Binding
+	SortSnippets     key.Binding
+	DeleteSnippet    key.Binding
+	EditSnippet      key.Binding
+	CopySnippet      key.Binding
+	CopyAllFiles     key.Binding
+	NextFragment     key.Binding
+	PreviousFragment key.Binding
+	PasteSnippet     key.Binding
+	SetFolder        key.Binding
+	RenameSnippet    key.Binding
+	RestoreSnippet   key.Binding
+	TagSnippet       key.Binding
+	MarkSnippet      key.Binding
+	VisualMode       key.Binding
+	MoveToFolder     key.Binding
+	FavoriteSnippet  key.Binding
+	ExportSnippets   key.Binding
+	RunSnippet       key.Binding
+	ToggleMarkdown   key.Binding
+	CompareSnippet   key.Binding
+	FindInContent    key.Binding
+	NextMatch        key.Binding
+	PreviousMatch    key.Binding
+	GotoLine         key.Binding
+	ToggleWrap       key.Binding
+	ScrollLeft       key.Binding
+	ScrollRight      key.Binding
+	CursorUp         key.Binding
+	CursorDown       key.Binding
+	SelectLines      key.Binding
+	YankLines        key.Binding
+	Undo             key.Binding
+	Redo             key.Binding
+	Overwrite        key.Binding
+	AutoSuffix       key.Binding
+	Confirm          key.Binding
+	Cancel           key.Binding
+	NextPane         key.Binding
+	PreviousPane     key.Binding
+	ChangeFolder     key.Binding
+	NewFolder        key.Binding
+	RenameFolder     key.Binding
+	DeleteFolder     key.Binding
+	MergeFolder      key.Binding
+	MoveFolderUp     key.Binding
+	MoveFolderDown   key.Binding
 }
 
 // DefaultKeyMap is the default key map for the application.
 var DefaultKeyMap = KeyMap{
-	Quit:            key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "exit")),
-	Search:          key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
-	ToggleHelp:      key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
-	NewSnippet:      key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "new")),
-	MoveSnippetDown: key.NewBinding(key.WithKeys("J"), key.WithHelp("J", "move snippet down")),
-	MoveSnippetUp:   key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "move snippet up")),
-	DeleteSnippet:   key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete")),
-	EditSnippet:     key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
-	CopySnippet:     key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
-	PasteSnippet:    key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "paste")),
-	RenameSnippet:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename snippet")),
-	SetFolder:       key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "rename folder")),
-	TagSnippet:      key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "tag"), key.WithDisabled()),
-	Confirm:         key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
-	Cancel:          key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
-	NextPane:        key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→/l", "go right")),
-	PreviousPane:    key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/h", "go left")),
-	ChangeFolder:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "change folder"), key.WithDisabled()),
+	Quit:             key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "exit")),
+	Search:           key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+	ToggleHelp:       key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
+	NewSnippet:       key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "new")),
+	MoveSnippetDown:  key.NewBinding(key.WithKeys("J"), key.WithHelp("J", "move snippet down")),
+	MoveSnippetUp:    key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "move snippet up")),
+	SortSnippets:     key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "sort")),
+	DeleteSnippet:    key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete")),
+	EditSnippet:      key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+	CopySnippet:      key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+	CopyAllFiles:     key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "copy all files"), key.WithDisabled()),
+	NextFragment:     key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "next file"), key.WithDisabled()),
+	PreviousFragment: key.NewBinding(key.WithKeys("["), key.WithHelp("[", "previous file"), key.WithDisabled()),
+	PasteSnippet:     key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "paste")),
+	RenameSnippet:    key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename snippet")),
+	RestoreSnippet:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "restore"), key.WithDisabled()),
+	SetFolder:        key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "set folder")),
+	TagSnippet:       key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "tag"), key.WithDisabled()),
+	MarkSnippet:      key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "mark")),
+	VisualMode:       key.NewBinding(key.WithKeys("V"), key.WithHelp("V", "mark range")),
+	MoveToFolder:     key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "move to folder")),
+	FavoriteSnippet:  key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "favorite")),
+	ExportSnippets:   key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "export")),
+	RunSnippet:       key.NewBinding(key.WithKeys("!"), key.WithHelp("!", "run")),
+	ToggleMarkdown:   key.NewBinding(key.WithKeys("M"), key.WithHelp("M", "raw/rendered"), key.WithDisabled()),
+	CompareSnippet:   key.NewBinding(key.WithKeys("="), key.WithHelp("=", "compare"), key.WithDisabled()),
+	FindInContent:    key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "find"), key.WithDisabled()),
+	NextMatch:        key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next match"), key.WithDisabled()),
+	PreviousMatch:    key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "previous match"), key.WithDisabled()),
+	GotoLine:         key.NewBinding(key.WithKeys(":"), key.WithHelp(":", "go to line"), key.WithDisabled()),
+	ToggleWrap:       key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "wrap lines"), key.WithDisabled()),
+	ScrollLeft:       key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "scroll left"), key.WithDisabled()),
+	ScrollRight:      key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "scroll right"), key.WithDisabled()),
+	CursorUp:         key.NewBinding(key.WithKeys("k", "up"), key.WithHelp("↑/k", "line up"), key.WithDisabled()),
+	CursorDown:       key.NewBinding(key.WithKeys("j", "down"), key.WithHelp("↓/j", "line down"), key.WithDisabled()),
+	SelectLines:      key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "select lines"), key.WithDisabled()),
+	YankLines:        key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy lines"), key.WithDisabled()),
+	Undo:             key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "undo"), key.WithDisabled()),
+	Redo:             key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "redo"), key.WithDisabled()),
+	Overwrite:        key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "overwrite")),
+	AutoSuffix:       key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "add suffix")),
+	Confirm:          key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
+	Cancel:           key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+	NextPane:         key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→/l", "go right")),
+	PreviousPane:     key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←/h", "go left")),
+	ChangeFolder:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "change folder"), key.WithDisabled()),
+	NewFolder:        key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "new folder"), key.WithDisabled()),
+	RenameFolder:     key.NewBinding(key.WithKeys("r", "R"), key.WithHelp("r", "rename folder"), key.WithDisabled()),
+	DeleteFolder:     key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete folder"), key.WithDisabled()),
+	MergeFolder:      key.NewBinding(key.WithKeys("M"), key.WithHelp("M", "merge folder"), key.WithDisabled()),
+	MoveFolderUp:     key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "move folder up"), key.WithDisabled()),
+	MoveFolderDown:   key.NewBinding(key.WithKeys("J"), key.WithHelp("J", "move folder down"), key.WithDisabled()),
 }
 
 // ShortHelp returns a quick help menu.
@@ -52,10 +120,14 @@ func (k KeyMap) ShortHelp() []key.Binding {
 		// k.PreviousPane,
 		// k.NextPane,
 		k.NewSnippet,
+		k.NewFolder,
+		k.RenameFolder,
+		k.DeleteFolder,
 		k.EditSnippet,
 		k.RenameSnippet,
 		k.SetFolder,
 		k.Search,
+		k.FindInContent,
 		k.DeleteSnippet,
 		k.CopySnippet,
 		k.ToggleHelp,
@@ -66,8 +138,14 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.NewSnippet, k.EditSnippet, k.PasteSnippet, k.CopySnippet, k.DeleteSnippet},
-		{k.MoveSnippetDown, k.MoveSnippetUp},
-		{k.RenameSnippet, k.SetFolder, k.TagSnippet},
+		{k.MoveSnippetDown, k.MoveSnippetUp, k.SortSnippets, k.MarkSnippet, k.VisualMode},
+		{k.MoveToFolder, k.FavoriteSnippet, k.ExportSnippets, k.RunSnippet, k.ToggleMarkdown, k.CompareSnippet},
+		{k.NextFragment, k.PreviousFragment, k.CopyAllFiles},
+		{k.RenameSnippet, k.SetFolder, k.TagSnippet, k.RestoreSnippet},
+		{k.FindInContent, k.NextMatch, k.PreviousMatch, k.GotoLine, k.ToggleWrap, k.ScrollLeft, k.ScrollRight},
+		{k.CursorUp, k.CursorDown, k.SelectLines, k.YankLines},
+		{k.Undo, k.Redo},
+		{k.NewFolder, k.RenameFolder, k.DeleteFolder, k.MergeFolder, k.MoveFolderUp, k.MoveFolderDown},
 		{k.NextPane, k.PreviousPane},
 		{k.Search, k.ToggleHelp, k.Quit},
 	}
@@ -14,13 +14,17 @@ import (
 
 // FilterValue is the snippet filter value that can be used when searching.
 func (s Snippet) FilterValue() string {
-	return s.Folder + "/" + s.Name + "\n" + "+" + strings.Join(s.Tags, "+") + "\n" + s.Language
+	return s.Folder + "/" + s.Name + "\n" + "+" + strings.Join(s.Tags, "+") + "\n" + s.Language + "\n" + s.Description
 }
 
 // snippetDelegate represents the snippet list item.
 type snippetDelegate struct {
 	styles SnippetsBaseStyle
 	state  state
+	// marked holds the paths of the snippets marked for bulk actions.
+	marked map[string]bool
+	// usage holds when the snippets were used.
+	usage Usage
 }
 
 // Height is the number of lines the snippet list item takes up.
@@ -60,18 +64,34 @@ func (d snippetDelegate) Render(w io.Writer, m list.Model, index int, item list.
 	if d.state == copyingState {
 		titleStyle = d.styles.CopiedTitle
 		subtitleStyle = d.styles.CopiedSubtitle
-	} else if d.state == deletingState {
+	} else if d.state == deletingState || d.state == conflictingState {
 		titleStyle = d.styles.DeletedTitle
 		subtitleStyle = d.styles.DeletedSubtitle
 	}
 
+	gutter := "  "
+	if d.marked[s.Path()] {
+		gutter = d.styles.Marked.Render("┃") + " "
+	}
+	name := s.Name
+	if s.Favorite {
+		name = "★ " + name
+	}
+
+	subtitle := Folder(s.Folder).Title() + " • " + humanizeTime(s.Date)
+	if n := d.usage.count(s.Path()); n == 1 {
+		subtitle += " • 1 use"
+	} else if n > 1 {
+		subtitle += fmt.Sprintf(" • %d uses", n)
+	}
+
 	if index == m.Index() {
-		fmt.Fprintln(w, "  "+titleStyle.Render(truncate.Truncate(s.Name, 30, "...", truncate.PositionEnd)))
-		fmt.Fprint(w, "  "+subtitleStyle.Render(s.Folder+" • "+humanizeTime(s.Date)))
+		fmt.Fprintln(w, gutter+titleStyle.Render(truncate.Truncate(name, 30, "...", truncate.PositionEnd)))
+		fmt.Fprint(w, gutter+subtitleStyle.Render(subtitle))
 		return
 	}
-	fmt.Fprintln(w, "  "+d.styles.UnselectedTitle.Render(truncate.Truncate(s.Name, 30, "...", truncate.PositionEnd)))
-	fmt.Fprint(w, "  "+d.styles.UnselectedSubtitle.Render(s.Folder+" • "+humanizeTime(s.Date)))
+	fmt.Fprintln(w, gutter+d.styles.UnselectedTitle.Render(truncate.Truncate(name, 30, "...", truncate.PositionEnd)))
+	fmt.Fprint(w, gutter+d.styles.UnselectedSubtitle.Render(subtitle))
 }
 
 // Folder represents a group of snippets in a directory.
@@ -82,6 +102,25 @@ func (f Folder) FilterValue() string {
 	return string(f)
 }
 
+// virtual reports whether the folder is not a directory of snippets but a view
+// provided by nap, such as the Trash.
+func (f Folder) virtual() bool {
+	return strings.HasPrefix(string(f), ".")
+}
+
+// Title returns the name of the folder displayed to the user.
+func (f Folder) Title() string {
+	switch f {
+	case trashFolder:
+		return "Trash"
+	case recentFolder:
+		return "Recent"
+	case frequentFolder:
+		return "Frequent"
+	}
+	return string(f)
+}
+
 // folderDelegate represents a folder list item.
 type folderDelegate struct{ styles FoldersBaseStyle }
 
@@ -109,10 +148,10 @@ func (d folderDelegate) Render(w io.Writer, m list.Model, index int, item list.I
 	}
 	fmt.Fprint(w, "  ")
 	if index == m.Index() {
-		fmt.Fprint(w, d.styles.Selected.Render("→ "+string(f)))
+		fmt.Fprint(w, d.styles.Selected.Render("→ "+f.Title()))
 		return
 	}
-	fmt.Fprint(w, d.styles.Unselected.Render("  "+string(f)))
+	fmt.Fprint(w, d.styles.Unselected.Render("  "+f.Title()))
 }
 
 const (
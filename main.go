@@ -1,26 +1,22 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/mattn/go-isatty"
-
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/sahilm/fuzzy"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
@@ -31,46 +27,122 @@ https://github.com/isabelroses/nap
 
 Usage:
   nap           - for interactive mode
+  nap --session <name> - for interactive mode in a named session
   nap list      - list all snippets
+  nap list --sort <manual|name|date|language|recent>
   nap <snippet> - print snippet to stdout
+  nap --exact <folder/name.ext> - print snippet only if the path matches exactly
+  nap --raw <snippet> - print snippet without highlighting or rendering Markdown
+  nap show <snippet> --lines 10-25 - print a range of lines of the snippet
+  nap show <snippet> --file <file> - print a file of a multi-file snippet
+  nap materialize <snippet> <dir> - write the files of the snippet to a directory
+  nap diff <snippet> <snippet> - print the differences between two snippets
+  nap dedupe [-y]     - find duplicate snippets and merge them into one
+  nap scan [--dry-run] - add the files created in the home directory and remove the deleted or ignored ones
+
+Scaffold:
+  nap use <snippet> [dest]      - write snippet to the current directory or dest, expanding {{placeholders}}
+  nap use <folder>/ [dest]      - write all snippets of the folder as a project skeleton
+  nap use --set name=value --force <snippet> - set a placeholder and overwrite existing files
+
+Shell:
+  nap pick                      - pick a snippet and print it to stdout
+  nap shell-init bash|zsh|fish  - print the ctrl+x ctrl+n key binding to insert a picked snippet
+  nap completion bash|zsh|fish  - print the shell completion script
+
+Run:
+  nap run <snippet> [-- args] - run snippet with the runner of its language
+  nap run -y <snippet>        - run snippet without confirmation
+
+Trash:
+  nap trash list              - list deleted snippets
+  nap trash restore <snippet> - restore a deleted snippet
+  nap trash empty             - permanently delete all deleted snippets
 
 Create:
   nap < main.go                 - save snippet from stdin
-  nap example/main.go < main.go - save snippet with name`)
+  nap example/main.go < main.go - save snippet with name
+  nap example/main.go --append|--force|--no-clobber < main.go - add to, replace or keep an existing snippet
+  nap example/main.go --tags a,b --desc "..." --lang go --fav < main.go - save snippet with metadata
+  nap images/logo.png --attach < logo.png - store binary content as an attachment
+  nap add <file|dir|glob...>    - import files as snippets, recursing into directories
+  nap add --from-url <url> [folder/name.ext] - import a snippet from an http or https URL`)
 
 func main() {
-	runCLI(os.Args[1:])
+	os.Exit(runCLI(os.Args[1:]))
 }
 
-func runCLI(args []string) {
+// runCLI runs nap with the command line arguments and returns the exit code.
+func runCLI(args []string) int {
 	config := readConfig()
 	snippets := readSnippets(config)
+	if len(args) > 0 && args[0] == "__complete" {
+		runComplete(args[1:], config, snippets)
+		return 0
+	}
 	snippets = migrateSnippets(config, snippets)
+	if len(args) > 0 && args[0] == "scan" {
+		// scan before anything else does, which --dry-run must not
+		return runScan(args[1:], config, snippets)
+	}
 	snippets = scanSnippets(config, snippets)
+	snippets = identifySnippets(config, snippets)
+	purgeExpiredTrash(config)
 
-	stdin := readStdin()
+	session := os.Getenv("NAP_SESSION")
+	if len(args) > 1 && args[0] == "--session" {
+		session = args[1]
+		args = args[2:]
+	}
+
+	stdin, err := readStdin(config.MaxStdinSize)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 	if stdin != "" {
-		saveSnippet(stdin, args, config, snippets)
-		return
+		return saveSnippet(stdin, args, config, snippets)
 	}
 
 	if len(args) > 0 {
 		switch args[0] {
 		case "list":
-			listSnippets(snippets)
+			listSnippets(args[1:], config, snippets)
+		case "trash":
+			runTrash(args[1:], config, snippets)
+		case "show":
+			return runShow(args[1:], config, snippets)
+		case "diff":
+			return runDiff(args[1:], config, snippets)
+		case "dedupe":
+			return runDedupe(args[1:], config, snippets)
+		case "add":
+			return runAdd(args[1:], config, snippets)
+		case "materialize":
+			return runMaterialize(args[1:], config, snippets)
+		case "use":
+			return runUse(args[1:], config, snippets)
+		case "run":
+			return runCommand(args[1:], config, snippets)
+		case "pick":
+			runPicker(config, snippets)
+		case "shell-init":
+			runShellInit(args[1:])
+		case "completion":
+			runCompletion(args[1:])
 		case "-h", "--help":
 			fmt.Println(helpText)
 		default:
-			snippet := findSnippet(args[0], snippets)
-			fmt.Print(snippet.Content(isatty.IsTerminal(os.Stdout.Fd())))
+			return printSnippet(args, config, snippets)
 		}
-		return
+		return 0
 	}
 
-	err := runInteractiveMode(config, snippets)
-	if err != nil {
+	if err := runInteractiveMode(config, snippets, session); err != nil {
 		fmt.Println("Alas, there's been an error", err)
+		return 1
 	}
+	return 0
 }
 
 // parseName returns a folder, name, and language for the given name.
@@ -108,32 +180,13 @@ func parseName(s string) (string, string, string) {
 	return folder, name, language
 }
 
-// readStdin returns the stdin that is piped in to the command line interface.
-func readStdin() string {
-	stat, err := os.Stdin.Stat()
-	if err != nil {
-		return ""
+// outsideHome reports whether the folder or snippet name would be stored
+// outside the home directory, because it is absolute or contains "..".
+func outsideHome(name string) bool {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return true
 	}
-
-	if stat.Mode()&os.ModeCharDevice != 0 {
-		return ""
-	}
-
-	reader := bufio.NewReader(os.Stdin)
-	var b strings.Builder
-
-	for {
-		r, _, err := reader.ReadRune()
-		if err != nil && err == io.EOF {
-			break
-		}
-		_, err = b.WriteRune(r)
-		if err != nil {
-			return ""
-		}
-	}
-
-	return b.String()
+	return slices.Contains(strings.Split(filepath.ToSlash(name), "/"), "..")
 }
 
 // readSnippets returns all the snippets read from the snippets.json file.
@@ -194,110 +247,333 @@ func migrateSnippets(config Config, snippets []Snippet) []Snippet {
 	return snippets
 }
 
+// identifySnippets gives an ID and a hash to the snippets saved before
+// snippets had them.
+func identifySnippets(config Config, snippets []Snippet) []Snippet {
+	var modified bool
+	for idx := range snippets {
+		if snippets[idx].ID == "" {
+			snippets[idx].ID = newSnippetID()
+			modified = true
+		}
+		if snippets[idx].Hash == "" {
+			hashSnippet(config, &snippets[idx])
+			modified = modified || snippets[idx].Hash != ""
+		}
+	}
+	if modified {
+		writeSnippets(config, snippets)
+	}
+	return snippets
+}
+
 // scanSnippets scans for any new/removed snippets and adds them to snippets.json
 func scanSnippets(config Config, snippets []Snippet) []Snippet {
 	var modified bool
-	snippetExists := func(path string) bool {
-		for _, snippet := range snippets {
-			if path == snippet.Path() {
-				return true
-			}
+	found, err := findNewSnippets(config, snippets)
+	if err != nil {
+		fmt.Printf("could not scan config home: %v\n", err)
+	}
+	if len(found) > 0 {
+		snippets = append(snippets, found...)
+		modified = true
+	}
+
+	// only write the snippets when they changed, which keeps startup fast
+	if missing := findMissingSnippets(config, snippets); len(missing) > 0 {
+		gone := map[string]bool{}
+		for _, s := range missing {
+			gone[s.Path()] = true
 		}
-		return false
+		snippets = slices.DeleteFunc(snippets, func(s Snippet) bool { return gone[s.Path()] })
+		modified = true
+	}
+
+	if modified {
+		writeSnippets(config, snippets)
+	}
+
+	return snippets
+}
+
+// findNewSnippets returns the snippets for the files of the folders in the
+// home directory that are not among the snippets yet and not ignored,
+// ordered after the others of their folder. Folders that cannot be read are
+// skipped, and the last error met is returned with the snippets found.
+func findNewSnippets(config Config, snippets []Snippet) ([]Snippet, error) {
+	known := map[string]bool{}
+	for _, snippet := range snippets {
+		known[snippet.Path()] = true
 	}
 
 	homeEntries, err := os.ReadDir(config.Home)
 	if err != nil {
-		fmt.Printf("could not scan config home: %v\n", err)
-		return snippets
+		return nil, err
 	}
+	ignore := readIgnore(config)
 
+	var found []Snippet
 	for _, homeEntry := range homeEntries {
 		if !homeEntry.IsDir() {
 			continue
 		}
-		if strings.HasPrefix(homeEntry.Name(), ".") {
+		if strings.HasPrefix(homeEntry.Name(), ".") || ignore.ignored(homeEntry.Name(), true) {
 			continue
 		}
 
 		folderPath := filepath.Join(config.Home, homeEntry.Name())
-		folderEntries, err := os.ReadDir(folderPath)
-		if err != nil {
-			fmt.Printf("could not scan %q: %v\n", folderPath, err)
+		folderEntries, readErr := os.ReadDir(folderPath)
+		if readErr != nil {
+			err = readErr
 			continue
 		}
 
+		_, hi := folderOrderBounds(snippets, homeEntry.Name())
 		for _, folderEntry := range folderEntries {
-			if folderEntry.IsDir() {
+			if strings.HasPrefix(folderEntry.Name(), ".") && folderEntry.IsDir() {
 				continue
 			}
 
 			snippetPath := filepath.Join(homeEntry.Name(), folderEntry.Name())
-			if !snippetExists(snippetPath) {
-				name := folderEntry.Name()
-				ext := filepath.Ext(name)
-				snippets = append(snippets, Snippet{
-					Folder:   homeEntry.Name(),
-					Date:     time.Now(),
-					Name:     strings.TrimSuffix(name, ext),
-					File:     name,
-					Language: strings.TrimPrefix(ext, "."),
-					Tags:     make([]string, 0),
-				})
-				modified = true
+			if known[snippetPath] || ignore.ignored(filepath.ToSlash(snippetPath), folderEntry.IsDir()) {
+				continue
+			}
+			name := folderEntry.Name()
+			ext := filepath.Ext(name)
+			hi++
+			snippet := Snippet{
+				Folder:   homeEntry.Name(),
+				Date:     time.Now(),
+				Name:     strings.TrimSuffix(name, ext),
+				File:     name,
+				Language: strings.TrimPrefix(ext, "."),
+				Tags:     make([]string, 0),
+				Order:    hi,
+				ID:       newSnippetID(),
 			}
+			// directories are snippets made of several files
+			if folderEntry.IsDir() {
+				snippet.Name, snippet.Language, snippet.Multi = name, "", true
+			}
+			hashSnippet(config, &snippet)
+			found = append(found, snippet)
 		}
 	}
+	return found, err
+}
 
-	var idx int
+// findMissingSnippets returns the snippets whose file is gone or is now
+// ignored.
+func findMissingSnippets(config Config, snippets []Snippet) []Snippet {
+	ignore := readIgnore(config)
+	var missing []Snippet
 	for _, snippet := range snippets {
-		snippetPath := filepath.Join(config.Home, snippet.Path())
-		if _, err := os.Stat(snippetPath); !errors.Is(err, fs.ErrNotExist) {
-			snippets[idx] = snippet
-			idx++
-			modified = true
+		if ignore.ignored(filepath.ToSlash(snippet.Path()), snippet.Multi) {
+			missing = append(missing, snippet)
+		} else if _, err := os.Stat(filepath.Join(config.Home, snippet.Path())); errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, snippet)
 		}
 	}
-	snippets = snippets[:idx]
+	return missing
+}
 
-	if modified {
-		writeSnippets(config, snippets)
+// saveFlags are the flags of the commands saving snippets, setting what
+// happens to existing snippets and the metadata of the saved ones.
+type saveFlags struct {
+	appendContent *bool
+	force         *bool
+	noClobber     *bool
+	tags          *string
+	desc          *string
+	lang          *string
+	fav           *bool
+	attach        *bool
+}
+
+// addSaveFlags defines the flags of the commands saving snippets.
+func addSaveFlags(flags *flag.FlagSet) saveFlags {
+	return saveFlags{
+		appendContent: flags.Bool("append", false, "append to the snippet if it exists"),
+		force:         flags.Bool("force", false, "overwrite the snippet if it exists"),
+		noClobber:     flags.Bool("no-clobber", false, "leave the snippet as is if it exists, without failing"),
+		tags:          flags.String("tags", "", "comma separated tags to add to the snippet"),
+		desc:          flags.String("desc", "", "description of the snippet"),
+		lang:          flags.String("lang", "", "language of the snippet, instead of the extension of its name"),
+		fav:           flags.Bool("fav", false, "mark the snippet as a favorite"),
+		attach:        flags.Bool("attach", false, "store binary content as an attachment instead of refusing it"),
 	}
+}
 
-	return snippets
+// valid reports whether at most one of --append, --force and --no-clobber is
+// given, printing an error otherwise.
+func (f saveFlags) valid() bool {
+	policies := 0
+	for _, set := range []bool{*f.appendContent, *f.force, *f.noClobber} {
+		if set {
+			policies++
+		}
+	}
+	if policies > 1 {
+		fmt.Println("only one of --append, --force and --no-clobber can be given")
+		return false
+	}
+	return true
 }
 
-func saveSnippet(content string, args []string, config Config, snippets []Snippet) {
-	// Save snippet to location
-	name := defaultSnippetName
-	if len(args) > 0 {
-		name = strings.Join(args, " ")
+// newSnippet returns the snippet to save in the folder, with the language
+// given by --lang if any.
+func (f saveFlags) newSnippet(folder, name, language string) Snippet {
+	if *f.lang != "" {
+		language = *f.lang
 	}
+	s := Snippet{
+		Folder:   folder,
+		Date:     time.Now(),
+		Name:     name,
+		Language: language,
+		Tags:     []string{},
+		ID:       newSnippetID(),
+	}
+	s.File = s.fileName()
+	return s
+}
 
-	folder, name, language := parseName(name)
-	file := fmt.Sprintf("%s.%s", name, language)
-	filePath := filepath.Join(config.Home, folder, file)
+// errSnippetExists is returned when saving a snippet would replace another
+// one without --force or --append.
+var errSnippetExists = errors.New("already exists, use --force to overwrite it or --append to add to it")
+
+// storeSnippet writes the content of the snippet and returns the snippets
+// with its metadata. An existing snippet at the same path is left as is
+// unless --force or --append is given, in which case its metadata is updated
+// rather than duplicated. It returns false when --no-clobber kept the
+// existing snippet.
+func storeSnippet(config Config, snippets []Snippet, snippet Snippet, content string, f saveFlags) ([]Snippet, Snippet, bool, error) {
+	i := slices.IndexFunc(snippets, func(s Snippet) bool { return s.Path() == snippet.Path() })
+	if i >= 0 || snippetPathTaken(config, snippets, snippet.Path()) {
+		switch {
+		case *f.noClobber:
+			return snippets, snippet, false, nil
+		case !*f.force && !*f.appendContent:
+			return snippets, snippet, false, errSnippetExists
+		}
+		if i >= 0 {
+			source, attachment := snippet.Source, snippet.Attachment
+			snippet = snippets[i]
+			snippet.Date = time.Now()
+			if source != "" {
+				snippet.Source = source
+			}
+			// appended content only makes the snippet binary
+			snippet.Attachment = attachment || (*f.appendContent && snippet.Attachment)
+		}
+	}
+	if !*f.appendContent {
+		if dup, ok := findDuplicate(config, snippets, []byte(content)); ok && dup.Path() != snippet.Path() {
+			fmt.Fprintf(os.Stderr, "warning: %s has the same content\n", dup)
+		}
+	}
+
+	for _, tag := range strings.Split(*f.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(snippet.Tags, tag) {
+			snippet.Tags = append(snippet.Tags, tag)
+		}
+	}
+	if *f.desc != "" {
+		snippet.Description = *f.desc
+	}
+	snippet.Favorite = snippet.Favorite || *f.fav
+
+	filePath := filepath.Join(config.Home, snippet.Path())
 	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-		fmt.Println("unable to create folder")
-		return
+		return snippets, snippet, false, fmt.Errorf("unable to create folder: %w", err)
+	}
+	mode := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if *f.appendContent {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(filePath, mode, 0o644)
+	if err == nil {
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
 	}
-	err := os.WriteFile(filePath, []byte(content), 0o644)
 	if err != nil {
-		fmt.Println("unable to create snippet")
+		return snippets, snippet, false, fmt.Errorf("unable to create snippet: %w", err)
+	}
+	if *f.appendContent {
+		hashSnippet(config, &snippet)
+	} else {
+		snippet.Hash = contentHash([]byte(content))
+	}
+
+	if i >= 0 {
+		snippets[i] = snippet
+	} else {
+		// Add snippet metadata at the top of its folder
+		lo, _ := folderOrderBounds(snippets, snippet.Folder)
+		snippet.Order = lo - 1
+		snippets = append(snippets, snippet)
+	}
+	return snippets, snippet, true, nil
+}
+
+// printSaveError prints why the snippet could not be saved.
+func printSaveError(s Snippet, err error) {
+	if errors.Is(err, errSnippetExists) {
+		fmt.Printf("%s %v\n", s, err)
 		return
 	}
+	fmt.Printf("could not save %s: %v\n", s, err)
+}
 
-	// Add snippet metadata
-	snippet := Snippet{
-		Folder:   folder,
-		Date:     time.Now(),
-		Name:     name,
-		File:     file,
-		Language: language,
+// saveSnippet saves the content read from stdin as the snippet named by the
+// arguments and returns the exit code. Untitled snippets are saved with a
+// numeric suffix rather than replacing each other.
+func saveSnippet(content string, args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("nap", flag.ContinueOnError)
+	save := addSaveFlags(flags)
+	args, err := parseInterspersed(flags, args)
+	if err != nil || !save.valid() {
+		return 2
 	}
 
-	snippets = append([]Snippet{snippet}, snippets...)
+	binary := isBinary(content)
+	if binary && !*save.attach {
+		fmt.Fprintln(os.Stderr, "stdin looks binary, pass --attach to store it as an attachment")
+		return 1
+	}
+
+	name := defaultSnippetName
+	if len(args) > 0 {
+		name = strings.Join(args, " ")
+	}
+	if outsideHome(name) {
+		fmt.Fprintf(os.Stderr, "%s is outside the home directory\n", name)
+		return 1
+	}
+	ext := filepath.Ext(name)
+	folder, name, language := parseName(name)
+	if binary && ext == "" {
+		language = attachmentLanguage
+	}
+	snippet := save.newSnippet(folder, name, language)
+	snippet.Attachment = binary
+	if len(args) == 0 {
+		snippet = uniqueSnippet(snippet, func(path string) bool { return snippetPathTaken(config, snippets, path) })
+	}
+
+	snippets, snippet, saved, err := storeSnippet(config, snippets, snippet, content, save)
+	if err != nil {
+		printSaveError(snippet, err)
+		return 1
+	}
+	if !saved {
+		fmt.Printf("%s already exists, leaving it as is\n", snippet)
+		return 0
+	}
 	writeSnippets(config, snippets)
+	return 0
 }
 
 func writeSnippets(config Config, snippets []Snippet) {
@@ -312,27 +588,73 @@ func writeSnippets(config Config, snippets []Snippet) {
 	}
 }
 
-func listSnippets(snippets []Snippet) {
-	for _, snippet := range snippets {
-		fmt.Println(snippet)
+func listSnippets(args []string, config Config, snippets []Snippet) {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	sortName := flags.String("sort", sortManual.String(), "sort by "+strings.Join(sortModes, ", "))
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	mode, err := parseSortMode(*sortName)
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-}
 
-func findSnippet(search string, snippets []Snippet) Snippet {
-	matches := fuzzy.FindFrom(search, Snippets{snippets})
-	if len(matches) > 0 {
-		return snippets[matches[0].Index]
+	snippets = slices.Clone(snippets)
+	if mode == sortManual {
+		order := readFolderOrder(config)
+		slices.SortStableFunc(snippets, func(a, b Snippet) int {
+			if a.Folder != b.Folder {
+				return compareFolders(order, Folder(a.Folder), Folder(b.Folder))
+			}
+			return a.Order - b.Order
+		})
+	} else {
+		sortSnippets(readState().Usage, snippets, mode)
+	}
+	for _, snippet := range snippets {
+		fmt.Println(snippet)
 	}
-	return Snippet{}
 }
 
-func runInteractiveMode(config Config, snippets []Snippet) error {
+func runInteractiveMode(config Config, snippets []Snippet, session string) error {
 	if len(snippets) == 0 {
 		// welcome to nap!
 		snippets = append(snippets, defaultSnippet)
 	}
 	state := readState()
+	state.Session = state.session(session)
+	m := newModel(config, snippets, state)
+	m.session = session
+	if watcher, err := watchHome(config); err == nil {
+		m.watcher = watcher
+		defer watcher.Close()
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	model, err := p.Run()
+	if err != nil {
+		return err
+	}
+	fm, ok := model.(*Model)
+	if !ok {
+		return err
+	}
+	b, err := json.Marshal(fm.allSnippets())
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(config.Home, config.File), b, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return writeFolderOrder(config, fm.snippetFolders())
+}
 
+// newModel builds the application model for the given snippets, restoring the
+// session from the previous run's state.
+func newModel(config Config, snippets []Snippet, state State) *Model {
+	snippets = slices.Clone(snippets)
+	sortSnippets(state.Usage, snippets, sortManual)
 	folders := make(map[Folder][]list.Item)
 	for _, snippet := range snippets {
 		folders[Folder(snippet.Folder)] = append(folders[Folder(snippet.Folder)], list.Item(snippet))
@@ -340,15 +662,29 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 
 	defaultStyles := DefaultStyles(config)
 
+	for _, folder := range scanFolders(config) {
+		if _, ok := folders[folder]; !ok {
+			folders[folder] = nil
+		}
+	}
+	if len(folders) == 0 {
+		folders[Folder(defaultSnippetFolder)] = nil
+	}
+
+	var trashItems []list.Item
+	for _, t := range readTrash(config) {
+		trashItems = append([]list.Item{t.item()}, trashItems...)
+	}
+	folders[Folder(trashFolder)] = trashItems
+	folders[Folder(recentFolder)] = nil
+	folders[Folder(frequentFolder)] = nil
+
 	var folderItems []list.Item
-	foldersSlice := maps.Keys(folders)
-	slices.Sort(foldersSlice)
+	folderOrder := readFolderOrder(config)
+	foldersSlice := orderFolders(folderOrder, maps.Keys(folders))
 	for _, folder := range foldersSlice {
 		folderItems = append(folderItems, list.Item(folder))
 	}
-	if len(folderItems) <= 0 {
-		folderItems = append(folderItems, list.Item(Folder(defaultSnippetFolder)))
-	}
 	folderList := list.New(folderItems, folderDelegate{defaultStyles.Folders.Blurred}, 0, 0)
 	folderList.Title = "Folders"
 
@@ -359,29 +695,12 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 	folderList.Styles.NoItems = lipgloss.NewStyle().Margin(0, 2).Foreground(lipgloss.Color(config.GrayColor))
 	folderList.SetStatusBarItemName("folder", "folders")
 
-	for idx, folder := range foldersSlice {
-		if string(folder) == state.CurrentFolder {
-			folderList.Select(idx)
-			break
-		}
-	}
-
 	content := viewport.New(80, 0)
 
 	lists := map[Folder]*list.Model{}
 
-	currentFolder := folderList.SelectedItem().(Folder)
 	for folder, items := range folders {
-		snippetList := newList(items, 20, defaultStyles.Snippets.Focused)
-		if folder == currentFolder {
-			for idx, item := range snippetList.Items() {
-				if s, ok := item.(Snippet); ok && s.File == state.CurrentSnippet {
-					snippetList.Select(idx)
-					break
-				}
-			}
-		}
-		lists[folder] = snippetList
+		lists[folder] = newList(items, 20, defaultStyles.Snippets.Focused)
 	}
 
 	shit_help := help.New()
@@ -398,6 +717,7 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 	m := &Model{
 		Lists:        lists,
 		Folders:      folderList,
+		folderOrder:  folderOrder,
 		Code:         content,
 		ContentStyle: defaultStyles.Content.Blurred,
 		ListStyle:    defaultStyles.Snippets.Focused,
@@ -405,38 +725,25 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 		keys:         DefaultKeyMap,
 		help:         shit_help,
 		config:       config,
+		usage:        state.Usage,
+		started:      time.Now(),
+		stored:       snippetsByPath(snippets),
 		inputs: []textinput.Model{
 			newTextInput(defaultSnippetFolder + " "),
 			newTextInput(defaultSnippetName),
 		},
 		tagsInput: newTextInput("Tags"),
 	}
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	model, err := p.Run()
-	if err != nil {
-		return err
-	}
-	fm, ok := model.(*Model)
-	if !ok {
-		return err
-	}
-	var allSnippets []list.Item
-	for _, list := range fm.Lists {
-		allSnippets = append(allSnippets, list.Items()...)
-	}
-	b, err := json.Marshal(allSnippets)
-	if err != nil {
-		return err
-	}
-	err = os.WriteFile(filepath.Join(config.Home, config.File), b, os.ModePerm)
-	if err != nil {
-		return err
+	if m.usage == nil {
+		m.usage = Usage{}
 	}
-	return nil
+	m.updateUsageViews()
+	m.restoreSession(state.Session)
+	return m
 }
 
 func newList(items []list.Item, height int, styles SnippetsBaseStyle) *list.Model {
-	snippetList := list.New(items, snippetDelegate{styles, navigatingState}, 25, height)
+	snippetList := list.New(items, snippetDelegate{styles, navigatingState, nil, nil}, 25, height)
 	snippetList.SetShowHelp(false)
 	snippetList.SetShowFilter(false)
 	snippetList.SetShowTitle(false)
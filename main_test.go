@@ -1,10 +1,18 @@
 package main
 
 import (
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
 )
 
 func TestCLI(t *testing.T) {
@@ -138,5 +146,973 @@ func tmpHome(t *testing.T) string {
 		t.Log("could not set NAP_HOME")
 		t.FailNow()
 	}
+	if err := os.Setenv("NAP_STATE", filepath.Join(t.TempDir(), "state.json")); err != nil {
+		t.Log("could not set NAP_STATE")
+		t.FailNow()
+	}
 	return tmp
 }
+
+func TestTrash(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "bar.baz"), []byte("foo bar baz"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	snippets := scanSnippets(cfg, readSnippets(cfg))
+
+	trashed, err := trashSnippet(cfg, snippets[0])
+	if err != nil {
+		t.Logf("could not trash snippet: %v", err)
+		t.FailNow()
+	}
+	writeSnippets(cfg, nil)
+
+	t.Run("list", func(t *testing.T) {
+		out := captureStdout(t, func() { runCLI([]string{"trash", "list"}) })
+		if !strings.HasPrefix(out, "foo/bar.baz (deleted ") {
+			t.Logf("unexpected trash listing: %q", out)
+			t.FailNow()
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		captureStdout(t, func() { runCLI([]string{"trash", "restore", "foo/bar.baz"}) })
+		if _, err := os.Stat(filepath.Join(tmp, "foo", "bar.baz")); err != nil {
+			t.Logf("snippet was not restored: %v", err)
+			t.FailNow()
+		}
+		if n := len(readTrash(cfg)); n != 0 {
+			t.Logf("restored snippet is still in the trash: got %d items", n)
+			t.FailNow()
+		}
+		if n := len(readSnippets(cfg)); n != 1 {
+			t.Logf("restored snippet is missing from the metadata: got %d snippets", n)
+			t.FailNow()
+		}
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		trashed, err = trashSnippet(cfg, snippets[0])
+		if err != nil {
+			t.Logf("could not trash snippet: %v", err)
+			t.FailNow()
+		}
+		trashed.DeletedAt = time.Now().Add(-31 * Day)
+		if err := writeTrash(cfg, []TrashedSnippet{trashed}); err != nil {
+			t.Logf("could not write trash: %v", err)
+			t.FailNow()
+		}
+		purgeExpiredTrash(cfg)
+		if _, err := os.Stat(filepath.Join(tmp, trashed.Path())); err == nil {
+			t.Log("expired snippet was not purged")
+			t.FailNow()
+		}
+	})
+}
+
+func TestUsage(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet folder: %v", err)
+		t.FailNow()
+	}
+	for _, name := range []string{"bar.go", "baz.go"} {
+		if err := os.WriteFile(filepath.Join(tmp, "foo", name), []byte(name), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+
+	first := captureStdout(t, func() { runCLI([]string{"ba"}) })
+	other := "bar.go"
+	if first == other {
+		other = "baz.go"
+	}
+	for i := 0; i < 3; i++ {
+		captureStdout(t, func() { runCLI([]string{"foo/" + other}) })
+	}
+
+	usage := readState().Usage
+	if n := usage.count("foo/" + other); n != 3 {
+		t.Logf("prints were not recorded: got %d uses", n)
+		t.FailNow()
+	}
+	if out := captureStdout(t, func() { runCLI([]string{"ba"}) }); out != other {
+		t.Logf("most used snippet should rank first: got %q but want %q", out, other)
+		t.FailNow()
+	}
+
+	cfg := readConfig()
+	m := newModel(cfg, readSnippets(cfg), readState())
+	recent := listSnippetsOf(m.Lists[recentFolder])
+	if len(recent) != 2 || recent[0].File != other {
+		t.Logf("recent folder should list the last used snippet first: got %v", recent)
+		t.FailNow()
+	}
+
+	m.selectFolder("foo")
+	for i, s := range listSnippetsOf(m.Lists["foo"]) {
+		if s.File == first {
+			m.Lists["foo"].Select(i)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		m.copySnippets(m.targetSnippets())
+	}
+	frequent := listSnippetsOf(m.Lists[frequentFolder])
+	if len(frequent) != 2 || frequent[0].File != first {
+		t.Logf("frequent folder should list the most used snippet first: got %v", frequent)
+		t.FailNow()
+	}
+
+	// a print from another terminal while the TUI is open
+	captureStdout(t, func() { runCLI([]string{"foo/" + other}) })
+	m.saveState()
+	usage = readState().Usage
+	if usage.count("foo/"+first) != 5 || usage.count("foo/"+other) != 5 {
+		t.Logf("saving the TUI state should keep the usage recorded meanwhile: got %d and %d uses", usage.count("foo/"+first), usage.count("foo/"+other))
+		t.FailNow()
+	}
+
+	older, newer := time.Now().Add(-time.Hour), time.Now()
+	u := Usage{
+		"foo/a.go": {{Action: usageCopy, Time: newer}},
+		"foo/b.go": {{Action: usagePrint, Time: older}},
+	}
+	u.rename("foo/a.go", "foo/b.go")
+	if u.count("foo/b.go") != 2 || !u.last("foo/b.go").Equal(newer) {
+		t.Logf("renamed usage should be merged in time order: got %v", u["foo/b.go"])
+		t.FailNow()
+	}
+}
+
+func TestPick(t *testing.T) {
+	snippets := []Snippet{
+		{Folder: "foo", Name: "bar", Language: "go", File: "bar.go"},
+		{Folder: "foo", Name: "baz", Language: "sh", File: "baz.sh"},
+	}
+	usage := Usage{}
+	usage.record("foo/baz.sh", usagePrint)
+
+	p := newPicker(readConfig(), snippets, usage)
+	if p.matches[0].Name != "baz" {
+		t.Logf("most used snippet should be listed first: got %s", p.matches[0])
+		t.FailNow()
+	}
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("ba")},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyEnter},
+	} {
+		p.Update(msg)
+	}
+	if p.picked == nil || p.picked.Name != "bar" {
+		t.Logf("picked snippet is incorrect: got %v", p.picked)
+		t.FailNow()
+	}
+}
+
+func TestShellInit(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out := captureStdout(t, func() { runShellInit([]string{shell}) })
+		if !strings.Contains(out, "nap pick") {
+			t.Logf("%s script should call nap pick: got %q", shell, out)
+			t.FailNow()
+		}
+	}
+	if out := captureStdout(t, func() { runShellInit([]string{"csh"}) }); !strings.HasPrefix(out, "usage:") {
+		t.Logf("unknown shell should print the usage: got %q", out)
+		t.FailNow()
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tmp := tmpHome(t)
+	for _, path := range []string{"foo/bar.go", "foo/baz.sh", "qux/quux.py"} {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create snippet folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(path), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	cfg := readConfig()
+	writeSnippets(cfg, scanSnippets(cfg, nil))
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{Name: "subcommands", Args: []string{"li"}, Want: "list\n"},
+		{Name: "folders", Args: []string{"q"}, Want: "qux/\nqux/quux.py\n"},
+		{Name: "snippets", Args: []string{"foo/b"}, Want: "foo/bar.go\nfoo/baz.sh\n"},
+		{Name: "flags", Args: []string{"list", "--"}, Want: "--sort\n"},
+		{Name: "flag values", Args: []string{"list", "--sort", "n"}, Want: "name\n"},
+		{Name: "run", Args: []string{"run", "foo/baz"}, Want: "foo/baz.sh\n"},
+		{Name: "run args", Args: []string{"run", "foo/baz.sh", ""}, Want: ""},
+		{Name: "shells", Args: []string{"completion", ""}, Want: "bash\nfish\nzsh\n"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			out := captureStdout(t, func() { runCLI(append([]string{"__complete"}, tc.Args...)) })
+			if out != tc.Want {
+				t.Logf("completion is incorrect: got %q but want %q", out, tc.Want)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tmp := tmpHome(t)
+	for _, path := range []string{"foo/bar.go", "foo/bar.sh", "foo/barbaz.go", "misc/foo.sh", "misc/format_output_options.go", "fonts/list.sh"} {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create snippet folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(path), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+		Code int
+	}{
+		{Name: "exact path", Args: []string{"foo/bar.sh"}, Want: "foo/bar.sh", Code: 0},
+		{Name: "exact flag", Args: []string{"--exact", "foo/bar.go"}, Want: "foo/bar.go", Code: 0},
+		{Name: "exact flag without match", Args: []string{"--exact", "barbaz"}, Code: 1},
+		{Name: "fuzzy", Args: []string{"barbaz"}, Want: "foo/barbaz.go", Code: 0},
+		{Name: "no match", Args: []string{"qux"}, Code: 1},
+		{Name: "typo", Args: []string{"fooo"}, Code: 1},
+		{Name: "near miss", Args: []string{"fol"}, Code: 1},
+		{Name: "word starts", Args: []string{"mfoo"}, Want: "misc/format_output_options.go", Code: 0},
+		{Name: "flags after the snippet", Args: []string{"foo/bar.sh", "--raw", "--exact"}, Want: "foo/bar.sh", Code: 0},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var code int
+			out := captureStdout(t, func() { code = runCLI(tc.Args) })
+			if out != tc.Want || code != tc.Code {
+				t.Logf("lookup is incorrect: got %q (exit %d) but want %q (exit %d)", out, code, tc.Want, tc.Code)
+				t.FailNow()
+			}
+		})
+	}
+
+	t.Run("usage boost", func(t *testing.T) {
+		snippets := []Snippet{
+			{Folder: "misc", Name: "domain_models", Language: "go", File: "domain_models.go"},
+			{Folder: "go", Name: "main", Language: "go", File: "main.go"},
+		}
+		usage := Usage{}
+		for i := 0; i < maxUsageEvents; i++ {
+			usage.record(snippets[0].Path(), usagePrint)
+		}
+		matches, _ := matchSnippets("main", snippets, usage, false)
+		if len(matches) != 2 || matches[0].snippet.Name != "main" {
+			t.Logf("usage should not put a loose match before a much better one: got %v", matches)
+			t.FailNow()
+		}
+	})
+
+	t.Run("choose", func(t *testing.T) {
+		snippets := []Snippet{{Folder: "foo", Name: "bar", Language: "go"}, {Folder: "foo", Name: "bar", Language: "sh"}}
+		var out strings.Builder
+		s, ok := chooseSnippet(snippets, strings.NewReader("2\n"), &out)
+		if !ok || s.Language != "sh" {
+			t.Logf("chosen snippet is incorrect: got %s", s)
+			t.FailNow()
+		}
+		if _, ok := chooseSnippet(snippets, strings.NewReader("3\n"), &out); ok {
+			t.Log("an invalid choice should be refused")
+			t.FailNow()
+		}
+	})
+}
+
+func TestShow(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "lines.txt"), []byte("one\ntwo\nthree\nfour\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+
+	tt := []struct {
+		Name string
+		Args []string
+		Want string
+		Code int
+	}{
+		{Name: "whole snippet", Args: []string{"show", "foo/lines.txt"}, Want: "one\ntwo\nthree\nfour\n"},
+		{Name: "range", Args: []string{"show", "foo/lines.txt", "--lines", "2-3"}, Want: "two\nthree\n"},
+		{Name: "single line", Args: []string{"show", "--lines", "2", "lines"}, Want: "two\n"},
+		{Name: "open range", Args: []string{"show", "lines", "--lines", "3-"}, Want: "three\nfour\n"},
+		{Name: "past the end", Args: []string{"show", "lines", "--lines", "2-10"}, Want: "two\nthree\nfour\n"},
+		{Name: "start past the end", Args: []string{"show", "lines", "--lines", "5-10"}, Code: 1},
+		{Name: "invalid range", Args: []string{"show", "lines", "--lines", "3-2"}, Code: 1},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var code int
+			out := captureStdout(t, func() { code = runCLI(tc.Args) })
+			if out != tc.Want || code != tc.Code {
+				t.Logf("show is incorrect: got %q (exit %d) but want %q (exit %d)", out, code, tc.Want, tc.Code)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tmp := tmpHome(t)
+	for path, content := range map[string]string{"foo/a.txt": "one\ntwo\n", "bar/a.txt": "one\nthree\n", "bar/b.txt": "one\ntwo\n"} {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create snippet folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(content), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI([]string{"diff", "foo/a.txt", "bar/a.txt"}) })
+	if want := "--- foo/a.txt\n+++ bar/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+three\n"; out != want || code != 1 {
+		t.Logf("diff is incorrect: got %q (exit %d)", out, code)
+		t.FailNow()
+	}
+	out = captureStdout(t, func() { code = runCLI([]string{"diff", "foo/a.txt", "bar/b.txt"}) })
+	if out != "" || code != 0 {
+		t.Logf("identical snippets should not differ: got %q (exit %d)", out, code)
+		t.FailNow()
+	}
+	if code = runCLI([]string{"diff", "foo/a.txt", "qux"}); code != 2 {
+		t.Logf("diff with a missing snippet should fail: got exit %d", code)
+		t.FailNow()
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	tmp := tmpHome(t)
+	near := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	files := map[string]string{
+		"foo/a.txt": "same",
+		"bar/a.txt": "same",
+		"foo/x.go":  near,
+		"bar/x.go":  strings.Replace(near, "j", "  J", 1) + "\n\n",
+		"foo/z.go":  "unrelated",
+		"foo/e.go":  "",
+		"bar/e.go":  "",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create snippet folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(content), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	cfg := readConfig()
+	snippets := scanSnippets(cfg, nil)
+	for i := range snippets {
+		switch snippets[i].String() {
+		case "foo/a.txt":
+			snippets[i].Tags = []string{"one"}
+		case "bar/a.txt":
+			snippets[i].Tags = []string{"two"}
+			snippets[i].Favorite = true
+		case "foo/x.go":
+			snippets[i].Date = time.Now().Add(-time.Hour)
+		}
+	}
+	writeSnippets(cfg, snippets)
+
+	if dup, ok := findDuplicate(cfg, snippets, []byte("same")); !ok || dup.Name != "a" {
+		t.Logf("expected a duplicate of the content, got %s", dup)
+		t.FailNow()
+	}
+	if _, ok := findDuplicate(cfg, snippets, []byte("")); ok {
+		t.Log("empty snippets should not be duplicates")
+		t.FailNow()
+	}
+	// the stored hashes are compared rather than every snippet file
+	unhashed := slices.Clone(snippets)
+	for i := range unhashed {
+		unhashed[i].Hash = ""
+	}
+	if _, ok := findDuplicate(cfg, unhashed, []byte("same")); ok {
+		t.Log("snippets should be compared by their stored hash")
+		t.FailNow()
+	}
+	stale := slices.Clone(snippets)
+	for i := range stale {
+		stale[i].Hash = contentHash([]byte("changed since"))
+	}
+	if _, ok := findDuplicate(cfg, stale, []byte("changed since")); ok {
+		t.Log("a snippet changed since it was hashed should not be a duplicate")
+		t.FailNow()
+	}
+
+	groups := findDuplicates(cfg, snippets, defaultSimilarity)
+	if len(groups) != 2 {
+		t.Logf("expected 2 groups of duplicates, got %v", groups)
+		t.FailNow()
+	}
+	for _, g := range groups {
+		if g.exact != (g.snippets[0].Language == "txt") {
+			t.Logf("wrong kind of duplicates for %v", g.snippets)
+			t.FailNow()
+		}
+	}
+	if len(findDuplicates(cfg, snippets, 1)) != 1 {
+		t.Log("a threshold of 1 should only find exact duplicates")
+		t.FailNow()
+	}
+
+	captureStdout(t, func() { runCLI([]string{"dedupe", "-y"}) })
+	var kept []string
+	for _, s := range readSnippets(cfg) {
+		kept = append(kept, s.String())
+		if s.String() == "bar/a.txt" && (!s.Favorite || !slices.Equal(s.Tags, []string{"two", "one"})) {
+			t.Logf("expected the tags and favorite to be merged, got %v %v", s.Tags, s.Favorite)
+			t.FailNow()
+		}
+	}
+	slices.Sort(kept)
+	if want := []string{"bar/a.txt", "bar/e.go", "foo/e.go", "foo/x.go", "foo/z.go"}; !slices.Equal(kept, want) {
+		t.Logf("expected the favorite and oldest snippets to be kept, got %v", kept)
+		t.FailNow()
+	}
+	if n := len(readTrash(cfg)); n != 2 {
+		t.Logf("expected the duplicates in the trash, got %d", n)
+		t.FailNow()
+	}
+}
+
+func TestSave(t *testing.T) {
+	tmp := tmpHome(t)
+	cfg := readConfig()
+	save := func(content string, args ...string) int {
+		pipeStdin(t, content)
+		var code int
+		captureStdout(t, func() { code = runCLI(args) })
+		return code
+	}
+	content := func() string {
+		b, err := os.ReadFile(filepath.Join(tmp, "foo", "bar.sh"))
+		if err != nil {
+			t.Logf("could not read snippet: %v", err)
+			t.FailNow()
+		}
+		return string(b)
+	}
+
+	if code := save("one\n", "foo/bar", "--lang", "sh", "--tags", "a, b", "--desc", "says one", "--fav"); code != 0 {
+		t.Logf("could not save snippet: exit %d", code)
+		t.FailNow()
+	}
+	snippets := readSnippets(cfg)
+	if len(snippets) != 1 || snippets[0].String() != "foo/bar.sh" || !slices.Equal(snippets[0].Tags, []string{"a", "b"}) ||
+		snippets[0].Description != "says one" || !snippets[0].Favorite {
+		t.Logf("snippet metadata is incorrect: got %+v", snippets)
+		t.FailNow()
+	}
+	id := snippets[0].ID
+
+	if code := save("two\n", "foo/bar.sh"); code != 1 || content() != "one\n" {
+		t.Logf("existing snippet should not be overwritten: exit %d, got %q", code, content())
+		t.FailNow()
+	}
+	if code := save("two\n", "foo/bar.sh", "--no-clobber"); code != 0 || content() != "one\n" {
+		t.Logf("existing snippet should be kept with --no-clobber: exit %d, got %q", code, content())
+		t.FailNow()
+	}
+	if code := save("two\n", "--append", "foo/bar.sh", "--tags", "c"); code != 0 || content() != "one\ntwo\n" {
+		t.Logf("content should be appended: exit %d, got %q", code, content())
+		t.FailNow()
+	}
+	if hash := readSnippets(cfg)[0].Hash; hash != contentHash([]byte("one\ntwo\n")) {
+		t.Logf("the hash of the whole snippet should be saved, got %q", hash)
+		t.FailNow()
+	}
+	if code := save("three\n", "foo/bar.sh", "--force"); code != 0 || content() != "three\n" {
+		t.Logf("snippet should be overwritten with --force: exit %d, got %q", code, content())
+		t.FailNow()
+	}
+	snippets = readSnippets(cfg)
+	if len(snippets) != 1 || snippets[0].ID != id || !slices.Equal(snippets[0].Tags, []string{"a", "b", "c"}) || !snippets[0].Favorite ||
+		snippets[0].Hash != contentHash([]byte("three\n")) {
+		t.Logf("existing snippet metadata should be updated: got %+v", snippets)
+		t.FailNow()
+	}
+	if code := save("x", "foo/bar.sh", "--force", "--append"); code != 2 {
+		t.Logf("policies should be exclusive: exit %d", code)
+		t.FailNow()
+	}
+
+	save("untitled")
+	save("untitled")
+	if n := len(readSnippets(cfg)); n != 3 {
+		t.Logf("untitled snippets should not replace each other: got %d snippets", n)
+		t.FailNow()
+	}
+
+	latin1 := "caf\xe9\r\n"
+	save(latin1, "foo/latin1.txt")
+	if b, _ := os.ReadFile(filepath.Join(tmp, "foo", "latin1.txt")); string(b) != latin1 {
+		t.Logf("bytes should be saved as is: got %q", b)
+		t.FailNow()
+	}
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	if code := save(png, "foo/logo"); code != 1 {
+		t.Logf("binary content should be refused: exit %d", code)
+		t.FailNow()
+	}
+	if code := save(png, "foo/logo", "--attach"); code != 0 {
+		t.Logf("binary content should be attached with --attach: exit %d", code)
+		t.FailNow()
+	}
+	s, ok := lookupSnippet("foo/logo.bin", readSnippets(cfg), true)
+	b, _ := os.ReadFile(filepath.Join(tmp, "foo", "logo.bin"))
+	if !ok || !s.Attachment || string(b) != png || s.Content(cfg, true) != png {
+		t.Logf("attachment should be stored as is: got %+v with %q", s, b)
+		t.FailNow()
+	}
+
+	for _, name := range []string{"../escaped", "/escaped"} {
+		if code := save("escaped\n", name); code != 1 {
+			t.Logf("%s should be refused for leaving the home directory: exit %d", name, code)
+			t.FailNow()
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "..", "escaped")); err == nil {
+		t.Log("snippet should not be written outside the home directory")
+		t.FailNow()
+	}
+
+	t.Setenv("NAP_MAX_STDIN_SIZE", "4")
+	if code := save("too large", "foo/large"); code != 1 {
+		t.Logf("stdin over the size limit should be refused: exit %d", code)
+		t.FailNow()
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tmp := tmpHome(t)
+	cfg := readConfig()
+	src := t.TempDir()
+	write := func(name, content string) {
+		file := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Logf("could not create directory: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Logf("could not write file: %v", err)
+			t.FailNow()
+		}
+	}
+	write("hello.go", "package main\n")
+	write("notes", "plain\n")
+	write("lib/a.py", "a\n")
+	write("lib/deep/b.py", "b\n")
+	write("lib/.hidden/c.py", "c\n")
+	add := func(args ...string) int {
+		var code int
+		captureStdout(t, func() { code = runCLI(append([]string{"add"}, args...)) })
+		return code
+	}
+	paths := func() []string {
+		var paths []string
+		for _, s := range readSnippets(cfg) {
+			paths = append(paths, s.String())
+		}
+		slices.Sort(paths)
+		return paths
+	}
+
+	if code := add(filepath.Join(src, "hello.go"), filepath.Join(src, "notes"), "--tags", "x"); code != 0 {
+		t.Logf("could not add files: exit %d", code)
+		t.FailNow()
+	}
+	if got := paths(); !slices.Equal(got, []string{"misc/hello.go", "misc/notes"}) {
+		t.Logf("name and language should be inferred from the files: got %v", got)
+		t.FailNow()
+	}
+	s, _ := lookupSnippet("misc/hello.go", readSnippets(cfg), true)
+	if s.Source != filepath.Join(src, "hello.go") || !slices.Equal(s.Tags, []string{"x"}) {
+		t.Logf("source and tags should be recorded: got %+v", s)
+		t.FailNow()
+	}
+
+	if code := add(filepath.Join(src, "lib")); code != 0 {
+		t.Logf("could not add directory: exit %d", code)
+		t.FailNow()
+	}
+	if got := paths(); !slices.Equal(got, []string{"lib/a.py", "lib/b.py", "misc/hello.go", "misc/notes"}) {
+		t.Logf("directory should be added recursively without hidden files: got %v", got)
+		t.FailNow()
+	}
+	if code := add(filepath.Join(src, "lib", "a.py"), "--folder", "lib"); code != 1 {
+		t.Logf("existing snippet should not be overwritten: exit %d", code)
+		t.FailNow()
+	}
+
+	if code := add(filepath.Join(src, "**", "*.py"), "--folder", "py"); code != 0 {
+		t.Logf("could not add glob: exit %d", code)
+		t.FailNow()
+	}
+	if got := paths(); !slices.Contains(got, "py/a.py") || !slices.Contains(got, "py/b.py") || slices.Contains(got, "py/c.py") {
+		t.Logf("** should match files in any directory: got %v", got)
+		t.FailNow()
+	}
+	if code := add(filepath.Join(src, "*.rs")); code != 1 {
+		t.Logf("glob without matches should fail: exit %d", code)
+		t.FailNow()
+	}
+
+	write("docker/Dockerfile", "FROM alpine\n")
+	write("docker/.bashrc", "alias ll='ls -l'\n")
+	if code := add(filepath.Join(src, "docker", "Dockerfile"), filepath.Join(src, "docker", ".bashrc"), "--folder", "docker"); code != 0 {
+		t.Logf("could not add files without extension: exit %d", code)
+		t.FailNow()
+	}
+	s, ok := lookupSnippet("docker/Dockerfile", readSnippets(cfg), true)
+	if _, err := os.Stat(filepath.Join(tmp, "docker", "Dockerfile")); !ok || err != nil || s.syntax() != "Dockerfile" {
+		t.Logf("files without extension should keep their name: got %+v, %v", s, err)
+		t.FailNow()
+	}
+	if _, ok := lookupSnippet("docker/.bashrc", readSnippets(cfg), true); !ok {
+		t.Logf("a leading dot should not be taken for an extension: got %v", paths())
+		t.FailNow()
+	}
+
+	write("dup/x/main.go", "package x\n")
+	write("dup/y/main.go", "package y\n")
+	before := paths()
+	if code := add(filepath.Join(src, "dup"), "--force"); code != 1 || !slices.Equal(paths(), before) {
+		t.Logf("files added under the same name should fail before adding any: exit %d, got %v", code, paths())
+		t.FailNow()
+	}
+
+	for _, folder := range []string{"../escaped", "/escaped", "a/../../escaped"} {
+		if code := add(filepath.Join(src, "hello.go"), "--folder", folder); code != 1 {
+			t.Logf("folder %s should be refused for leaving the home directory: exit %d", folder, code)
+			t.FailNow()
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "..", "escaped")); err == nil || !slices.Equal(paths(), before) {
+		t.Logf("snippets should not be added outside the home directory: got %v", paths())
+		t.FailNow()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gist/fetch.js" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "fetch()\n")
+	}))
+	defer server.Close()
+	url := server.URL + "/gist/fetch.js"
+	if code := add("--from-url", url); code != 0 {
+		t.Logf("could not add URL: exit %d", code)
+		t.FailNow()
+	}
+	s, ok = lookupSnippet("misc/fetch.js", readSnippets(cfg), true)
+	b, _ := os.ReadFile(filepath.Join(tmp, "misc", "fetch.js"))
+	if !ok || s.Source != url || string(b) != "fetch()\n" {
+		t.Logf("snippet should be fetched from the URL: got %+v with %q", s, b)
+		t.FailNow()
+	}
+	if code := add("--from-url", url, "web/get"); code != 0 || !slices.Contains(paths(), "web/get.js") {
+		t.Logf("URL snippet should be saved with the name: exit %d, got %v", code, paths())
+		t.FailNow()
+	}
+	if code := add("--from-url", url, "../web/get"); code != 1 {
+		t.Logf("URL snippet name leaving the home directory should be refused: exit %d", code)
+		t.FailNow()
+	}
+	if code := add("--from-url", server.URL+"/missing"); code != 1 {
+		t.Logf("URL not found should fail: exit %d", code)
+		t.FailNow()
+	}
+	if code := add("--from-url", "ftp://example.com/x"); code != 1 {
+		t.Logf("only http and https URLs should be fetched: exit %d", code)
+		t.FailNow()
+	}
+	t.Setenv("NAP_MAX_STDIN_SIZE", "4")
+	if code := add("--from-url", url, "web/large"); code != 1 || slices.Contains(paths(), "web/large.js") {
+		t.Logf("URL content past max_stdin_size should fail: exit %d, got %v", code, paths())
+		t.FailNow()
+	}
+}
+
+func TestUse(t *testing.T) {
+	tmp := tmpHome(t)
+	files := map[string]string{
+		"go/main.go":                    "package {{pkg}}\n",
+		"skeleton/Makefile":             "build:\n\tgo build ./cmd/{{name}}\n",
+		"skeleton/cmd/{{name}}/main.go": "package main\n",
+		"skeleton/README.md":            "# {{ name }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Logf("could not create directory: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	use := func(args ...string) int {
+		var code int
+		captureStdout(t, func() { code = runCLI(append([]string{"use"}, args...)) })
+		return code
+	}
+	read := func(path string) string {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Logf("could not read %s: %v", path, err)
+			t.FailNow()
+		}
+		return string(b)
+	}
+
+	dest := t.TempDir()
+	if code := use("go/main.go", dest); code != 1 {
+		t.Logf("missing placeholder values should fail: exit %d", code)
+		t.FailNow()
+	}
+	if code := use("go/main.go", dest, "--set", "pkg=app"); code != 0 || read(filepath.Join(dest, "main.go")) != "package app\n" {
+		t.Logf("snippet should be written with its placeholders expanded: exit %d", code)
+		t.FailNow()
+	}
+	if code := use("go/main.go", dest, "--set", "pkg=other"); code != 1 || read(filepath.Join(dest, "main.go")) != "package app\n" {
+		t.Logf("existing file should not be overwritten: exit %d", code)
+		t.FailNow()
+	}
+	if code := use("--force", "go/main.go", dest, "--set", "pkg=other"); code != 0 || read(filepath.Join(dest, "main.go")) != "package other\n" {
+		t.Logf("existing file should be overwritten with --force: exit %d", code)
+		t.FailNow()
+	}
+	if code := use("go/main.go", filepath.Join(dest, "app.go"), "--set", "pkg=app"); code != 0 || read(filepath.Join(dest, "app.go")) != "package app\n" {
+		t.Logf("snippet should be written to the dest file: exit %d", code)
+		t.FailNow()
+	}
+
+	project := filepath.Join(dest, "project")
+	if code := use("skeleton/", project, "--set", "name=tool"); code != 0 {
+		t.Logf("could not use folder template: exit %d", code)
+		t.FailNow()
+	}
+	if read(filepath.Join(project, "Makefile")) != "build:\n\tgo build ./cmd/tool\n" ||
+		read(filepath.Join(project, "cmd", "tool", "main.go")) != "package main\n" ||
+		read(filepath.Join(project, "README.md")) != "# tool\n" {
+		t.Log("folder template should be written with its relative paths and placeholders expanded")
+		t.FailNow()
+	}
+	other := filepath.Join(dest, "other")
+	if code := use("skeleton/", other, "--set", "name=../../escaped"); code != 1 {
+		t.Logf("placeholder values leaving the dest should fail: exit %d", code)
+		t.FailNow()
+	}
+	if _, err := os.Stat(filepath.Join(dest, "escaped")); err == nil {
+		t.Log("files should not be written outside the dest")
+		t.FailNow()
+	}
+	if code := use("nothing/", project); code != 1 {
+		t.Logf("empty folder template should fail: exit %d", code)
+		t.FailNow()
+	}
+}
+
+// pipeStdin replaces stdin with a pipe holding the content.
+func pipeStdin(t *testing.T, content string) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Logf("could not open pipe: %v", err)
+		t.FailNow()
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+	w.WriteString(content)
+	w.Close()
+}
+
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Logf("could not open pipe: %v", err)
+		t.FailNow()
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	fn()
+	w.Close()
+	os.Stdout = stdout
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Log("could not read stdout")
+		t.FailNow()
+	}
+	return string(out)
+}
+
+// benchHome returns the config of a new home directory holding n snippets
+// spread over a few folders.
+func benchHome(b *testing.B, n int) Config {
+	b.Helper()
+
+	tmp := b.TempDir()
+	b.Setenv("NAP_HOME", tmp)
+	b.Setenv("NAP_STATE", filepath.Join(b.TempDir(), "state.json"))
+	for i := 0; i < n; i++ {
+		folder := filepath.Join(tmp, fmt.Sprintf("folder%d", i%20))
+		if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+			b.Logf("could not create folder: %v", err)
+			b.FailNow()
+		}
+		content := fmt.Sprintf("package main\n\nfunc snippet%d() int {\n\treturn %d\n}\n", i, i)
+		if err := os.WriteFile(filepath.Join(folder, fmt.Sprintf("snippet%d.go", i)), []byte(content), 0o644); err != nil {
+			b.Logf("could not create snippet: %v", err)
+			b.FailNow()
+		}
+	}
+	return readConfig()
+}
+
+func BenchmarkScanSnippets(b *testing.B) {
+	cfg := benchHome(b, 5000)
+	snippets := scanSnippets(cfg, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanSnippets(cfg, snippets)
+	}
+}
+
+func BenchmarkNewModel(b *testing.B) {
+	cfg := benchHome(b, 5000)
+	snippets := scanSnippets(cfg, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newModel(cfg, snippets, State{})
+	}
+}
+
+func TestScanIgnore(t *testing.T) {
+	tmp := tmpHome(t)
+	cfg := readConfig()
+	for _, name := range []string{"misc/a.go", "misc/a.go~", "misc/.a.go.swp", "misc/app.log", "build/out.go"} {
+		file := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(file, []byte("x\n"), 0o644); err != nil {
+			t.Logf("could not create file: %v", err)
+			t.FailNow()
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, napignoreFile), []byte("*.log\nbuild/\n"), 0o644); err != nil {
+		t.Logf("could not write .napignore: %v", err)
+		t.FailNow()
+	}
+
+	var code int
+	out := captureStdout(t, func() { code = runCLI([]string{"scan", "--dry-run"}) })
+	if code != 0 || out != "would add misc/a.go\n" {
+		t.Logf("expected only misc/a.go to be added: exit %d, got %q", code, out)
+		t.FailNow()
+	}
+	if len(readSnippets(cfg)) != 0 {
+		t.Log("--dry-run should not add snippets")
+		t.FailNow()
+	}
+
+	out = captureStdout(t, func() { code = runCLI([]string{"scan"}) })
+	snippets := readSnippets(cfg)
+	if code != 0 || out != "added misc/a.go\n" || len(snippets) != 1 || snippets[0].ID == "" {
+		t.Logf("expected misc/a.go to be added: exit %d, got %q and %+v", code, out, snippets)
+		t.FailNow()
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, napignoreFile), []byte("*.log\nbuild/\nmisc/a.go\n"), 0o644); err != nil {
+		t.Logf("could not write .napignore: %v", err)
+		t.FailNow()
+	}
+	out = captureStdout(t, func() { code = runCLI([]string{"scan", "--dry-run"}) })
+	if code != 0 || out != "would remove misc/a.go\n" || len(readSnippets(cfg)) != 1 {
+		t.Logf("expected the ignored snippet to be reported: exit %d, got %q", code, out)
+		t.FailNow()
+	}
+	out = captureStdout(t, func() { code = runCLI([]string{"scan"}) })
+	if _, err := os.Stat(filepath.Join(tmp, "misc", "a.go")); code != 0 || out != "removed misc/a.go\n" || len(readSnippets(cfg)) != 0 || err != nil {
+		t.Logf("expected the ignored snippet to be dropped and its file kept: exit %d, got %q, %v", code, out, err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, napignoreFile), []byte("*.log\nbuild/\n"), 0o644); err != nil {
+		t.Logf("could not write .napignore: %v", err)
+		t.FailNow()
+	}
+	if out = captureStdout(t, func() { code = runCLI([]string{"scan"}) }); out != "added misc/a.go\n" {
+		t.Logf("expected misc/a.go to be added again, got %q", out)
+		t.FailNow()
+	}
+
+	if err := os.Remove(filepath.Join(tmp, "misc", "a.go")); err != nil {
+		t.Logf("could not remove snippet: %v", err)
+		t.FailNow()
+	}
+	out = captureStdout(t, func() { code = runCLI([]string{"scan", "--dry-run"}) })
+	if code != 0 || out != "would remove misc/a.go\n" {
+		t.Logf("expected misc/a.go to be removed: exit %d, got %q", code, out)
+		t.FailNow()
+	}
+
+	src := t.TempDir()
+	for _, name := range []string{"lib/b.py", "lib/b.log", "lib/build/c.py"} {
+		file := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Logf("could not create directory: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(file, []byte("x\n"), 0o644); err != nil {
+			t.Logf("could not write file: %v", err)
+			t.FailNow()
+		}
+	}
+	files, err := expandImports([]string{filepath.Join(src, "lib")}, "", readIgnore(cfg))
+	if err != nil || len(files) != 1 || filepath.Base(files[0].path) != "b.py" {
+		t.Logf("expected ignored files to be left out of imports: got %v, %v", files, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "github.com/charmbracelet/glamour"
+
+// markdownWidth is the width Markdown snippets are wrapped to when printed.
+const markdownWidth = 80
+
+// isMarkdown reports whether snippets of the language are Markdown documents.
+func isMarkdown(language string) bool {
+	return language == "md" || language == "markdown"
+}
+
+// renderMarkdown renders the Markdown content for the terminal, wrapped to the
+// width, with code blocks highlighted in the configured theme.
+func renderMarkdown(config Config, content string, width int) (string, error) {
+	style := glamour.DarkStyleConfig
+	style.CodeBlock.Theme = config.Theme
+	style.CodeBlock.Chroma = nil
+	r, err := glamour.NewTermRenderer(glamour.WithStyles(style), glamour.WithWordWrap(width))
+	if err != nil {
+		return "", err
+	}
+	return r.Render(content)
+}
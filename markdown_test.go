@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	content := "# Title\n\n- item\n\n```go\nfunc main() {}\n```\n"
+	rendered, err := renderMarkdown(Config{Theme: "dracula"}, content, 40)
+	if err != nil {
+		t.Logf("render: %v", err)
+		t.FailNow()
+	}
+	if strings.Contains(rendered, "# Title") || strings.Contains(rendered, "```") {
+		t.Logf("expected Markdown syntax to be rendered, got %q", rendered)
+		t.FailNow()
+	}
+	for _, want := range []string{"Title", "•", "func", "main"} {
+		if !strings.Contains(rendered, want) {
+			t.Logf("expected %q in %q", want, rendered)
+			t.FailNow()
+		}
+	}
+
+	if !isMarkdown("md") || !isMarkdown("markdown") || isMarkdown("go") {
+		t.Log("expected only md and markdown to be Markdown")
+		t.FailNow()
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"math/rand"
 	"os"
@@ -9,7 +8,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/alecthomas/chroma/v2/quick"
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -18,8 +16,6 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/exp/maps"
-	"golang.org/x/exp/slices"
 )
 
 const maxPane = 3
@@ -43,6 +39,8 @@ const (
 	quittingState
 	editingState
 	editingTagsState
+	conflictingState
+	promptingState
 )
 
 type input int
@@ -69,9 +67,24 @@ type Model struct {
 	Lists map[Folder]*list.Model
 	// the list of Folders to display to the user.
 	Folders list.Model
+	// the custom order of the folders.
+	folderOrder []Folder
+	// the name of the session restored on the next run.
+	session string
+	// the content scroll position restored once the content is loaded.
+	scroll int
+	// when each snippet was used, shown in the Recent and Frequent folders.
+	usage Usage
+	// when the application started, after which the usage recorded by other
+	// nap commands is merged in on quit.
+	started time.Time
+	// the order in which the snippets are listed.
+	sort sortMode
 	// the viewport of the Code snippet.
 	Code        viewport.Model
 	LineNumbers viewport.Model
+	// the snippet shown in the Code viewport.
+	content contentView
 	// the input for snippet folder, name, language
 	activeInput input
 	inputs      []textinput.Model
@@ -80,6 +93,31 @@ type Model struct {
 	pane pane
 	// the current state / action of the application.
 	state state
+	// the rename waiting for the user to resolve a name conflict.
+	pendingRename *renameOp
+	// the operations performed during the session, which can be undone.
+	history history
+	// the snippets marked for bulk actions.
+	selection selection
+	// the prompt asking the user for a value to complete an action.
+	prompt *prompt
+	// the snippet running with its output shown in the content pane.
+	run *run
+	// whether Markdown snippets are shown as is rather than rendered.
+	raw bool
+	// the snippet the selected snippet is compared with, side by side.
+	compare *Snippet
+	// the files of the multi-file snippet shown as tabs in the content pane.
+	fragments fragmentTabs
+	// the file shown in the content pane, which is being highlighted in the
+	// background unless it was highlighted before.
+	highlighting highlightKey
+	// the watcher of the home directory reloading the snippets changed
+	// outside nap, if watching is possible.
+	watcher *homeWatcher
+	// the snippets as last read from the snippets file by path, telling the
+	// metadata other nap commands changed from that changed here.
+	stored map[string]Snippet
 	// stying for components
 	ListStyle    SnippetsBaseStyle
 	FoldersStyle FoldersBaseStyle
@@ -91,16 +129,24 @@ func (m *Model) Init() tea.Cmd {
 	m.Folders.Styles.Title = m.FoldersStyle.Title
 	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
 	m.updateKeyMap()
+	m.updateActivePane(nil)
 
-	return func() tea.Msg {
+	update := func() tea.Msg {
 		return updateContentMsg(m.selectedSnippet())
 	}
+	if m.watcher != nil {
+		return tea.Batch(update, m.watcher.wait())
+	}
+	return update
 }
 
 // updateContentMsg tells the application to update the content view with the
 // given snippet.
 type updateContentMsg Snippet
 
+// editedMsg is sent when the editor of the snippet exits.
+type editedMsg Snippet
+
 // updateContent instructs the application to fetch the latest contents of the
 // snippet file.
 //
@@ -117,10 +163,10 @@ type updateFoldersMsg struct {
 }
 
 // updateFolders returns a Cmd to  tell the application that there are possible
-// folder changes to update.
-func (m *Model) updateFolders() tea.Cmd {
+// folder changes to update, selecting the given folder.
+func (m *Model) updateFolders(selected Folder) tea.Cmd {
+	msg := m.updateFoldersView(selected)
 	return func() tea.Msg {
-		msg := m.updateFoldersView()
 		return msg
 	}
 }
@@ -146,8 +192,35 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(setItemsCmd, cmd)
 	case updateContentMsg:
 		return m.updateContentView(msg)
+	case editedMsg:
+		m.rehashSnippet(Snippet(msg).Path())
+		return m.updateContentView(updateContentMsg(msg))
+	case highlightedMsg:
+		m.showHighlightedMsg(msg)
+		return m, nil
+	case homeChangedMsg:
+		// wait for renames, prompts and deletions to be done before
+		// reloading
+		if m.state != navigatingState {
+			return m, tea.Tick(watchDebounce, func(time.Time) tea.Msg { return homeChangedMsg{} })
+		}
+		return m, tea.Batch(m.reloadHome(), m.watcher.wait())
+	case runOutputMsg:
+		if msg.run != m.run {
+			return m, nil
+		}
+		m.run.output.WriteString(msg.output)
+		m.showRun()
+		return m, m.run.wait
+	case runDoneMsg:
+		if msg.run != m.run {
+			return m, nil
+		}
+		m.run.done = true
+		m.run.status = exitStatus(msg.err)
+		return m, nil
 	case changeStateMsg:
-		m.List().SetDelegate(snippetDelegate{m.ListStyle, msg.newState})
+		m.List().SetDelegate(snippetDelegate{m.ListStyle, msg.newState, m.selection.marked, m.usage})
 
 		var cmd tea.Cmd
 
@@ -170,11 +243,14 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			if wasEditing {
 				m.blurInputs()
-				i := m.List().Index()
 				snippet := m.selectedSnippet()
+				original := snippet
 				if m.inputs[nameInput].Value() != "" {
 					fullname := strings.Split(m.inputs[nameInput].Value(), ".")
-					if len(fullname) == 2 {
+					if snippet.Multi || (len(fullname) == 1 && snippet.Language == "") {
+						// keep directories and files without extension as they are
+						snippet.Name = m.inputs[nameInput].Value()
+					} else if len(fullname) == 2 {
 						snippet.Name = fullname[0]
 						snippet.Language = fullname[1]
 					} else {
@@ -186,30 +262,23 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					} else {
 						snippet.Folder = defaultSnippetFolder
 					}
-					file := fmt.Sprintf("%s.%s", snippet.Name, snippet.Language)
-					snippet.File = file
-					newPath := filepath.Join(m.config.Home, snippet.Path())
-					_ = os.MkdirAll(filepath.Dir(newPath), os.ModePerm)
-					_ = os.Rename(m.selectedSnippetFilePath(), newPath)
-					setCmd := m.List().SetItem(i, snippet)
-					m.pane = snippetPane
-					cmd = tea.Batch(setCmd, m.updateFolders(), m.updateContent())
+					snippet.File = snippet.fileName()
+					cmd = m.submitRename(&renameOp{from: original, to: snippet})
 				}
 			}
 		case pastingState:
 			content, err := clipboard.ReadAll()
-			if err != nil {
-				return m, changeState(navigatingState)
+			if err == nil {
+				err = m.pasteSnippet(m.selectedSnippet(), content)
 			}
-			f, err := os.OpenFile(m.selectedSnippetFilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 			if err != nil {
-				return m, changeState(navigatingState)
+				return m, tea.Batch(changeState(navigatingState), m.List().NewStatusMessage(fmt.Sprintf("Unable to paste: %v", err)))
 			}
-			defer f.Close()
-			f.WriteString(content)
 			return m, changeState(navigatingState)
 		case deletingState:
 			m.state = deletingState
+		case conflictingState:
+			m.pane = snippetPane
 		case editingState:
 			m.pane = contentPane
 			snippet := m.selectedSnippet()
@@ -217,7 +286,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if snippet.Name == defaultSnippetName {
 				m.inputs[nameInput].SetValue("")
 			} else {
-				m.inputs[nameInput].SetValue(snippet.Name + "." + snippet.Language)
+				m.inputs[nameInput].SetValue(snippet.fileName())
 			}
 			cmd = m.focusInput(m.activeInput)
 		case creatingState:
@@ -235,14 +304,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	case tea.WindowSizeMsg:
 		m.height = msg.Height - 4
-		for _, li := range m.Lists {
-			li.SetHeight(m.height)
-		}
-		m.Folders.SetHeight(m.height)
-		m.Code.Height = m.height
-		m.LineNumbers.Height = m.height
+		m.resize()
 		m.Code.Width = msg.Width - m.List().Width() - m.Folders.Width() - 20
 		m.LineNumbers.Width = 5
+		if len(m.content.lines) > 0 && m.run == nil {
+			m.showContent()
+		}
+		if m.scroll > 0 {
+			return m, m.updateContent()
+		}
 		return m, nil
 	case tea.KeyMsg:
 		if m.List().FilterState() == list.Filtering {
@@ -253,8 +323,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case deletingState:
 			switch {
 			case key.Matches(msg, m.keys.Confirm):
-				_ = os.Remove(m.selectedSnippetFilePath())
-				m.List().RemoveItem(m.List().Index())
+				if m.pane == folderPane {
+					if err := m.deleteFolder(m.selectedFolder()); err != nil {
+						return m, tea.Batch(changeState(navigatingState), m.List().NewStatusMessage(err.Error()))
+					}
+				} else if m.selectedFolder() == trashFolder {
+					_ = m.purgeSnippet(m.selectedSnippet())
+				} else {
+					_ = m.deleteSnippets(m.targetSnippets())
+				}
 				m.state = navigatingState
 				m.updateKeyMap()
 				return m, tea.Batch(changeState(navigatingState), func() tea.Msg {
@@ -266,6 +343,34 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case copyingState:
 			return m, changeState(navigatingState)
+		case promptingState:
+			switch msg.String() {
+			case "enter":
+				p := m.prompt
+				m.prompt = nil
+				return m, tea.Batch(changeState(navigatingState), p.submit(strings.TrimSpace(p.input.Value())))
+			case "esc":
+				m.prompt = nil
+				return m, changeState(navigatingState)
+			}
+			var cmd tea.Cmd
+			m.prompt.input, cmd = m.prompt.input.Update(msg)
+			return m, cmd
+		case conflictingState:
+			op := m.pendingRename
+			switch {
+			case key.Matches(msg, m.keys.Overwrite):
+				m.pendingRename = nil
+				return m, tea.Batch(changeState(navigatingState), m.commitRename(op, true))
+			case key.Matches(msg, m.keys.AutoSuffix):
+				m.pendingRename = nil
+				op.to = m.uniqueSnippet(op.to)
+				return m, tea.Batch(changeState(navigatingState), m.commitRename(op, false))
+			case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+				m.pendingRename = nil
+				return m, changeState(navigatingState)
+			}
+			return m, nil
 		case editingState:
 			if msg.String() == "esc" || msg.String() == "enter" {
 				return m, changeState(navigatingState)
@@ -285,18 +390,49 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.PreviousPane):
 			m.previousPane()
 		case key.Matches(msg, m.keys.Quit):
+			m.closeRun()
 			m.saveState()
 			m.state = quittingState
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.NewSnippet):
 			m.state = creatingState
 			return m, m.createNewSnippetFile()
+		case key.Matches(msg, m.keys.NewFolder):
+			return m, m.promptFor("New folder:", "name", func(name string) tea.Cmd {
+				return m.folderStatus("Created folder "+name, m.createFolder(Folder(name)))
+			})
+		case key.Matches(msg, m.keys.RenameFolder):
+			folder := m.selectedFolder()
+			return m, m.promptFor("Rename to:", string(folder), func(name string) tea.Cmd {
+				return m.folderStatus("Renamed folder to "+name, m.do(&folderRenameOp{from: folder, to: Folder(name)}))
+			})
+		case key.Matches(msg, m.keys.DeleteFolder):
+			return m, changeState(deletingState)
+		case key.Matches(msg, m.keys.MergeFolder):
+			folder := m.selectedFolder()
+			return m, m.promptFor("Merge into:", "folder", func(name string) tea.Cmd {
+				return m.folderStatus("Merged into "+name, m.mergeFolder(folder, Folder(name)))
+			})
+		case key.Matches(msg, m.keys.MoveFolderUp):
+			m.moveFolder(-1)
+		case key.Matches(msg, m.keys.MoveFolderDown):
+			m.moveFolder(1)
+		case key.Matches(msg, m.keys.SortSnippets):
+			m.cycleSort()
+			return m, tea.Batch(m.List().NewStatusMessage("Sorted by "+m.sort.String()), m.updateContent())
 		case key.Matches(msg, m.keys.MoveSnippetDown):
 			m.moveSnippetDown()
 		case key.Matches(msg, m.keys.MoveSnippetUp):
 			m.moveSnippetUp()
 		case key.Matches(msg, m.keys.PasteSnippet):
 			return m, changeState(pastingState)
+		case key.Matches(msg, m.keys.RestoreSnippet):
+			s, err := m.restoreSnippet(m.selectedSnippet())
+			if err != nil {
+				m.displayError(fmt.Sprintf("Unable to restore snippet: %v", err))
+				return m, nil
+			}
+			return m, tea.Batch(m.List().NewStatusMessage("Restored "+s.String()), m.updateContent())
 		case key.Matches(msg, m.keys.RenameSnippet):
 			m.activeInput = nameInput
 			return m, changeState(editingState)
@@ -306,29 +442,98 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		case key.Matches(msg, m.keys.ToggleHelp):
 			m.help.ShowAll = !m.help.ShowAll
-
-			var newHeight int
-			if m.help.ShowAll {
-				newHeight = m.height - 4
-			} else {
-				newHeight = m.height
-			}
-			m.List().SetHeight(newHeight)
-			m.Folders.SetHeight(newHeight)
-			m.Code.Height = newHeight
-			m.LineNumbers.Height = newHeight
+			m.resize()
 		case key.Matches(msg, m.keys.SetFolder):
 			m.activeInput = folderInput
 			return m, changeState(editingState)
-		case key.Matches(msg, m.keys.CopySnippet):
-			return m, func() tea.Msg {
-				content, err := os.ReadFile(m.selectedSnippetFilePath())
-				if err != nil {
-					return changeStateMsg{navigatingState}
-				}
-				clipboard.WriteAll(string(content))
-				return changeStateMsg{copyingState}
+		case key.Matches(msg, m.keys.Undo):
+			return m, m.undo()
+		case key.Matches(msg, m.keys.Redo):
+			return m, m.redo()
+		case key.Matches(msg, m.keys.CopySnippet) && m.selectedSnippet().Multi && !m.hasMarks():
+			return m, m.copyFragment()
+		case key.Matches(msg, m.keys.CopySnippet), key.Matches(msg, m.keys.CopyAllFiles):
+			return m, m.copySnippets(m.targetSnippets())
+		case key.Matches(msg, m.keys.NextFragment):
+			return m, m.switchFragment(1)
+		case key.Matches(msg, m.keys.PreviousFragment):
+			return m, m.switchFragment(-1)
+		case key.Matches(msg, m.keys.MarkSnippet):
+			m.pane = snippetPane
+			m.toggleMark()
+			m.List().CursorDown()
+			m.updateVisual()
+		case key.Matches(msg, m.keys.VisualMode):
+			m.pane = snippetPane
+			m.toggleVisual()
+		case key.Matches(msg, m.keys.Cancel) && m.hasMarks():
+			m.clearMarks()
+		case key.Matches(msg, m.keys.Cancel) && m.run != nil:
+			m.closeRun()
+			return m, m.updateContent()
+		case key.Matches(msg, m.keys.Cancel) && m.content.selecting:
+			m.toggleSelection()
+		case key.Matches(msg, m.keys.Cancel) && m.compare != nil:
+			return m, m.toggleCompare()
+		case key.Matches(msg, m.keys.CompareSnippet):
+			return m, m.toggleCompare()
+		case key.Matches(msg, m.keys.Cancel) && m.content.query != "":
+			m.content.search("")
+			m.showContent()
+		case key.Matches(msg, m.keys.ToggleMarkdown):
+			m.raw = !m.raw
+			return m, m.updateContent()
+		case key.Matches(msg, m.keys.FindInContent):
+			return m, m.promptFor("Find:", "text", m.findInContent)
+		case key.Matches(msg, m.keys.NextMatch):
+			m.nextMatch(1)
+		case key.Matches(msg, m.keys.PreviousMatch):
+			m.nextMatch(-1)
+		case key.Matches(msg, m.keys.GotoLine):
+			return m, m.promptFor("Go to line:", fmt.Sprintf("1-%d", len(m.content.lines)), m.gotoLine)
+		case key.Matches(msg, m.keys.ToggleWrap):
+			m.toggleWrap()
+		case key.Matches(msg, m.keys.ScrollLeft):
+			m.scrollHorizontal(-horizontalStep)
+		case key.Matches(msg, m.keys.ScrollRight):
+			m.scrollHorizontal(horizontalStep)
+		case key.Matches(msg, m.keys.CursorUp):
+			m.moveCursor(-1)
+			return m, nil
+		case key.Matches(msg, m.keys.CursorDown):
+			m.moveCursor(1)
+			return m, nil
+		case key.Matches(msg, m.keys.SelectLines):
+			m.toggleSelection()
+		case key.Matches(msg, m.keys.YankLines):
+			return m, m.yankLines()
+		case key.Matches(msg, m.keys.RunSnippet):
+			s := m.selectedSnippet()
+			placeholder := "args"
+			if names := placeholders(s.Content(m.config, false)); len(names) > 0 {
+				placeholder = strings.Join(names, " ")
 			}
+			return m, m.promptFor("Run "+s.Name+"?", placeholder, func(args string) tea.Cmd {
+				return m.runSnippet(s, strings.Fields(args))
+			})
+		case key.Matches(msg, m.keys.MoveToFolder):
+			snippets := m.targetSnippets()
+			return m, m.promptFor("Move to:", string(m.selectedFolder()), func(folder string) tea.Cmd {
+				return m.bulkStatus("Moved", len(snippets), m.moveSnippets(snippets, folder))
+			})
+		case key.Matches(msg, m.keys.TagSnippet):
+			snippets := m.targetSnippets()
+			return m, m.promptFor("Tags:", "tag -untag", func(tags string) tea.Cmd {
+				return m.bulkStatus("Tagged", len(snippets), m.tagSnippets(snippets, tags))
+			})
+		case key.Matches(msg, m.keys.FavoriteSnippet):
+			snippets := m.targetSnippets()
+			return m, m.bulkStatus("Updated", len(snippets), m.favoriteSnippets(snippets))
+		case key.Matches(msg, m.keys.ExportSnippets):
+			snippets := m.targetSnippets()
+			return m, m.promptFor("Export to:", "~/snippets", func(dir string) tea.Cmd {
+				return m.bulkStatus("Exported", len(snippets), exportSnippets(m.config, snippets, dir))
+			})
 		case key.Matches(msg, m.keys.DeleteSnippet):
 			m.pane = snippetPane
 			m.updateActivePane(msg)
@@ -363,7 +568,11 @@ func (m *Model) focusInput(i input) tea.Cmd {
 // selectedSnippetFilePath returns the file path of the snippet that is
 // currently selected.
 func (m *Model) selectedSnippetFilePath() string {
-	return filepath.Join(m.config.Home, m.selectedSnippet().Path())
+	s := m.selectedSnippet()
+	if s.Multi && m.fragments.snippet == s.Path() {
+		return filepath.Join(m.config.Home, s.Path(), filepath.FromSlash(m.fragments.activeName()))
+	}
+	return filepath.Join(m.config.Home, s.Path())
 }
 
 // nextPane sets the next pane to be active.
@@ -381,8 +590,9 @@ func (m *Model) previousPane() {
 
 // editSnippet opens the editor with the selected snippet file path.
 func (m *Model) editSnippet() tea.Cmd {
+	m.recordUsage([]Snippet{m.selectedSnippet()}, usageEdit)
 	return tea.ExecProcess(editorCmd(m.selectedSnippetFilePath()), func(err error) tea.Msg {
-		return updateContentMsg(m.selectedSnippet())
+		return editedMsg(m.selectedSnippet())
 	})
 }
 
@@ -395,33 +605,13 @@ func (m *Model) noContentHints() []keyHint {
 	}
 }
 
-// updateFolderView updates the folders list to display the current folders.
-func (m *Model) updateFoldersView() tea.Msg {
-	var selectedFolder Folder
+// updateFolderView updates the folders list to display the current folders,
+// selecting the given folder.
+func (m *Model) updateFoldersView(selectedFolder Folder) tea.Msg {
 	selectedFolderIndex := m.Folders.Index()
-	for folder, li := range m.Lists {
-		for i, item := range li.Items() {
-			snippet, ok := item.(Snippet)
-			if !ok {
-				continue
-			}
-			f := Folder(snippet.Folder)
-			_, ok = m.Lists[f]
-			if !ok {
-				m.Lists[f] = newList([]list.Item{}, m.height, m.ListStyle)
-				selectedFolder = f
-			}
-			if f != folder {
-				li.RemoveItem(i)
-				m.Lists[f].InsertItem(0, item)
-				selectedFolder = f
-			}
-		}
-	}
 	var folderItems []list.Item
 
-	foldersSlice := maps.Keys(m.Lists)
-	slices.Sort(foldersSlice)
+	foldersSlice := m.sortedFolders()
 	for i, folder := range foldersSlice {
 		folderItems = append(folderItems, Folder(folder))
 		if folder == selectedFolder {
@@ -438,6 +628,12 @@ func (m *Model) updateFoldersView() tea.Msg {
 // updateContentView updates the content view with the correct content based on
 // the active snippet or display the appropriate error message / hint message.
 func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
+	m.highlighting = highlightKey{}
+	if m.run != nil {
+		m.showRun()
+		return m, nil
+	}
+
 	if len(m.List().Items()) <= 0 {
 		m.displayKeyHint([]keyHint{
 			{"create a new snippet.", m.keys.NewSnippet},
@@ -445,8 +641,23 @@ func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	var b bytes.Buffer
-	content, err := os.ReadFile(filepath.Join(m.config.Home, Snippet(msg).Path()))
+	if m.compare != nil {
+		m.showCompare(Snippet(msg))
+		return m, nil
+	}
+
+	if msg.Multi {
+		return m, m.showFragment(Snippet(msg))
+	}
+	m.fragments = fragmentTabs{}
+
+	file := filepath.Join(m.config.Home, Snippet(msg).Path())
+	info, err := os.Stat(file)
+	if err != nil {
+		m.displayKeyHint(m.noContentHints())
+		return m, nil
+	}
+	content, err := os.ReadFile(file)
 	if err != nil {
 		m.displayKeyHint(m.noContentHints())
 		return m, nil
@@ -457,17 +668,33 @@ func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	// b.WriteString(string(content))
-	err = quick.Highlight(&b, string(content), msg.Language, "terminal16m", m.config.Theme)
-	if err != nil {
-		m.displayError("Unable to highlight file.")
+	if msg.Attachment {
+		m.displayError(fmt.Sprintf("Binary attachment, %d bytes.", len(content)))
 		return m, nil
 	}
 
-	s := b.String()
-	m.writeLineNumbers(lipgloss.Height(s))
-	m.Code.SetContent(s)
-	return m, nil
+	cmd := m.showHighlighted(file, info, string(content), Snippet(msg).syntax())
+	if m.scroll > 0 && m.Code.Height > 0 {
+		m.Code.SetYOffset(m.scroll)
+		m.LineNumbers.SetYOffset(m.scroll)
+		m.scroll = 0
+	}
+	return m, cmd
+}
+
+// resize sets the height of the panes, leaving room for the full help when
+// it is shown.
+func (m *Model) resize() {
+	height := m.height
+	if m.help.ShowAll {
+		height -= 4
+	}
+	for _, li := range m.Lists {
+		li.SetHeight(height)
+	}
+	m.Folders.SetHeight(height)
+	m.Code.Height = height
+	m.LineNumbers.Height = height
 }
 
 type keyHint struct {
@@ -478,6 +705,7 @@ type keyHint struct {
 // displayKeyHint updates the content viewport with instructions on the
 // relevent key binding that the user should most likely press.
 func (m *Model) displayKeyHint(hints []keyHint) {
+	m.content.clear()
 	m.LineNumbers.SetContent(strings.Repeat("  ~ \n", len(hints)))
 	var s strings.Builder
 	for _, hint := range hints {
@@ -492,20 +720,11 @@ func (m *Model) displayKeyHint(hints []keyHint) {
 
 // displayError updates the content viewport with the error message provided.
 func (m *Model) displayError(error string) {
+	m.content.clear()
 	m.LineNumbers.SetContent(" ~ ")
 	m.Code.SetContent(m.ContentStyle.EmptyHint.Render(error))
 }
 
-// writeLineNumbers writes the number of line numbers to the line number
-// viewport.
-func (m *Model) writeLineNumbers(n int) {
-	var lineNumbers strings.Builder
-	for i := 1; i < n; i++ {
-		lineNumbers.WriteString(fmt.Sprintf("%3d \n", i))
-	}
-	m.LineNumbers.SetContent(lineNumbers.String() + "  ~ \n")
-}
-
 const tabSpaces = 4
 
 // updateActivePane updates the currently active pane.
@@ -527,16 +746,19 @@ func (m *Model) updateActivePane(msg tea.Msg) tea.Cmd {
 		m.FoldersStyle = DefaultStyles(m.config).Folders.Blurred
 		*m.List(), cmd = (*m.List()).Update(msg)
 		cmds = append(cmds, cmd)
+		m.updateVisual()
 	case contentPane:
 		m.ListStyle = DefaultStyles(m.config).Snippets.Blurred
 		m.ContentStyle = DefaultStyles(m.config).Content.Focused
 		m.FoldersStyle = DefaultStyles(m.config).Folders.Blurred
 		m.Code, cmd = m.Code.Update(msg)
 		cmds = append(cmds, cmd)
-		m.LineNumbers, cmd = m.LineNumbers.Update(msg)
-		cmds = append(cmds, cmd)
+		if m.content.numbered {
+			m.keepCursorInView()
+		}
+		m.syncLineNumbers()
 	}
-	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state})
+	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state, m.selection.marked, m.usage})
 	m.Folders.SetDelegate(folderDelegate{m.FoldersStyle})
 	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
 	m.Folders.Styles.Title = m.FoldersStyle.Title
@@ -550,12 +772,77 @@ func (m *Model) updateKeyMap() {
 	hasItems := len(m.List().VisibleItems()) > 0
 	isFiltering := m.List().FilterState() == list.Filtering
 	isEditing := m.state == editingState
-	m.keys.DeleteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
+	inTrash := m.selectedFolder() == trashFolder
+	// the Recent and Frequent folders only list snippets of other folders
+	inView := m.selectedFolder().virtual() && !inTrash
+	inFolders := m.pane == folderPane
+	// the content pane keys apply to the snippet content, not to run output
+	inContent := m.pane == contentPane && m.run == nil && len(m.content.lines) > 0 && !isEditing
+	isRealFolder := !m.selectedFolder().virtual() && !isEditing
+	m.keys.NewFolder.SetEnabled(inFolders && !isEditing)
+	m.keys.RenameFolder.SetEnabled(inFolders && isRealFolder)
+	m.keys.DeleteFolder.SetEnabled(inFolders && isRealFolder)
+	m.keys.MergeFolder.SetEnabled(inFolders && isRealFolder)
+	m.keys.MoveFolderUp.SetEnabled(inFolders && isRealFolder)
+	m.keys.MoveFolderDown.SetEnabled(inFolders && isRealFolder)
+	m.keys.DeleteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders)
 	m.keys.CopySnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.PasteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.EditSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.NewSnippet.SetEnabled(!isFiltering && !isEditing)
+	// a multi-file snippet shows one of its files at a time
+	isMulti := hasItems && m.selectedSnippet().Multi && m.compare == nil && m.run == nil
+	if isMulti && !m.hasMarks() {
+		m.keys.CopySnippet.SetHelp("c", "copy file")
+	} else {
+		m.keys.CopySnippet.SetHelp("c", "copy")
+	}
+	m.keys.CopyAllFiles.SetEnabled(isMulti && !isFiltering && !isEditing)
+	m.keys.NextFragment.SetEnabled(isMulti && !isFiltering && !isEditing && len(m.fragments.names) > 1)
+	m.keys.PreviousFragment.SetEnabled(isMulti && !isFiltering && !isEditing && len(m.fragments.names) > 1)
+	// the clipboard is pasted at the end of a single file
+	m.keys.PasteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && !m.selectedSnippet().Multi)
+	m.keys.EditSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.RenameSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && !inFolders)
+	m.keys.SetFolder.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && !inFolders)
+	// the positions of a filtered list are not those of the folder
+	isManual := m.sort == sortManual && !m.List().IsFiltered()
+	m.keys.MoveSnippetUp.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && !inView && !inFolders && isManual)
+	m.keys.MoveSnippetDown.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && !inView && !inFolders && isManual)
+	m.keys.SortSnippets.SetEnabled(!isFiltering && !isEditing)
+	m.keys.RestoreSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && inTrash && !inFolders)
+	// space and f page through the lists and the content otherwise
+	inSnippets := m.pane == snippetPane
+	m.keys.MarkSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && inSnippets)
+	m.keys.VisualMode.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.MoveToFolder.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.TagSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash)
+	m.keys.FavoriteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && inSnippets)
+	m.keys.ExportSnippets.SetEnabled(hasItems && !isFiltering && !isEditing)
+	m.keys.RunSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inTrash && !inFolders && !m.selectedSnippet().Multi)
+	m.keys.Search.SetEnabled(!inContent)
+	m.keys.FindInContent.SetEnabled(inContent)
+	m.keys.NextMatch.SetEnabled(inContent && len(m.content.matches) > 0)
+	m.keys.PreviousMatch.SetEnabled(inContent && len(m.content.matches) > 0)
+	m.keys.GotoLine.SetEnabled(inContent)
+	m.keys.ToggleWrap.SetEnabled(inContent)
+	m.keys.ScrollLeft.SetEnabled(inContent && !m.content.wrap)
+	m.keys.ScrollRight.SetEnabled(inContent && !m.content.wrap)
+	m.keys.CursorUp.SetEnabled(inContent && m.content.numbered)
+	m.keys.CursorDown.SetEnabled(inContent && m.content.numbered)
+	m.keys.SelectLines.SetEnabled(inContent && m.content.numbered)
+	m.keys.YankLines.SetEnabled(inContent && m.content.numbered)
+	m.keys.CompareSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders)
+	m.keys.ToggleMarkdown.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders && isMarkdown(m.selectedSnippet().Language))
+	m.keys.NewSnippet.SetEnabled(!isFiltering && !isEditing && !inTrash && !inView && !inFolders)
 	m.keys.ChangeFolder.SetEnabled(m.pane == folderPane)
+	m.keys.Undo.SetEnabled(len(m.history.done) > 0 && !isFiltering && !isEditing)
+	m.keys.Redo.SetEnabled(len(m.history.undone) > 0 && !isFiltering && !isEditing)
+}
+
+// folderStatus returns a Cmd displaying the outcome of a folder action.
+func (m *Model) folderStatus(done string, err error) tea.Cmd {
+	if err != nil {
+		return m.List().NewStatusMessage(fmt.Sprintf("Unable to update folder: %v", err))
+	}
+	return tea.Batch(m.List().NewStatusMessage(done), m.refresh())
 }
 
 // selectedSnippet returns the currently selected snippet.
@@ -582,19 +869,11 @@ func (m *Model) List() *list.Model {
 }
 
 func (m *Model) moveSnippetDown() {
-	currentPosition := m.List().Index()
-	currentItem := m.List().SelectedItem()
-	m.List().InsertItem(currentPosition+2, currentItem)
-	m.List().RemoveItem(currentPosition)
-	m.List().CursorDown()
+	m.moveSnippet(m.List().Index() + 1)
 }
 
 func (m *Model) moveSnippetUp() {
-	currentPosition := m.List().Index()
-	currentItem := m.List().SelectedItem()
-	m.List().RemoveItem(currentPosition)
-	m.List().InsertItem(currentPosition-1, currentItem)
-	m.List().CursorUp()
+	m.moveSnippet(m.List().Index() - 1)
 }
 
 // createNewSnippet creates a new snippet file and adds it to the the list.
@@ -617,11 +896,19 @@ func (m *Model) createNewSnippetFile() tea.Cmd {
 			Language: lang,
 			Tags:     []string{},
 			Folder:   folder,
+			ID:       newSnippetID(),
 		}
 
 		_, _ = os.Create(filepath.Join(m.config.Home, newSnippet.Path()))
 
-		m.List().InsertItem(m.List().Index(), newSnippet)
+		if m.sort == sortManual {
+			m.List().InsertItem(m.List().Index(), newSnippet)
+			renumber(m.List())
+		} else {
+			_, hi := folderOrderBounds(listSnippetsOf(m.List()), folder)
+			newSnippet.Order = hi + 1
+			m.List().InsertItem(m.List().Index(), newSnippet)
+		}
 		return changeStateMsg{navigatingState}
 	}
 }
@@ -634,17 +921,51 @@ func (m *Model) View() string {
 
 	var (
 		folder   = m.ContentStyle.Title.Render(m.selectedSnippet().Folder)
-		name     = m.ContentStyle.Title.Render(m.selectedSnippet().Name + "." + m.selectedSnippet().Language)
+		name     = m.ContentStyle.Title.Render(m.selectedSnippet().fileName())
 		titleBar = m.ListStyle.TitleBar.Render("Snippets")
 	)
 
+	if m.sort != sortManual {
+		titleBar = m.ListStyle.TitleBar.Render("Snippets by " + m.sort.String())
+	}
+
+	if s := m.selectedSnippet(); s.Multi && m.fragments.snippet == s.Path() {
+		name = m.fragmentTabsView(s)
+	}
+
+	if m.content.query != "" && len(m.content.lines) > 0 {
+		name = m.ContentStyle.Title.Render(m.selectedSnippet().fileName() + " • " + m.content.matchStatus())
+	}
+
+	if m.compare != nil {
+		folder = m.ContentStyle.Title.Render("Compare")
+		name = m.ContentStyle.Title.Render(m.compare.String() + " ↔ " + m.selectedSnippet().String())
+	}
+
+	if m.run != nil {
+		folder = m.ContentStyle.Title.Render("Output")
+		name = m.ContentStyle.Title.Render(m.run.snippet.fileName() + " • " + m.runStatus())
+	}
+
 	if m.state == editingState {
 		folder = m.inputs[folderInput].View()
 		name = m.inputs[nameInput].View()
 	} else if m.state == copyingState {
 		titleBar = m.ListStyle.CopiedTitleBar.Render("Copied Snippet!")
+	} else if m.state == deletingState && m.pane == folderPane {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Delete Folder? (y/N)")
+	} else if m.state == deletingState && m.selectedFolder() == trashFolder {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Purge Snippet? (y/N)")
+	} else if m.state == deletingState && m.hasMarks() {
+		titleBar = m.ListStyle.DeletedTitleBar.Render(fmt.Sprintf("Delete %d Snippets? (y/N)", len(m.targetSnippets())))
 	} else if m.state == deletingState {
 		titleBar = m.ListStyle.DeletedTitleBar.Render("Delete Snippet? (y/N)")
+	} else if m.state == conflictingState {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Exists: (o)verwrite (s)uffix")
+	} else if m.state == promptingState {
+		titleBar = m.ListStyle.TitleBar.Render(m.prompt.input.View())
+	} else if m.hasMarks() {
+		titleBar = m.ListStyle.TitleBar.Render(fmt.Sprintf("%d Selected", len(m.selection.marked)))
 	} else if m.List().SettingFilter() {
 		titleBar = m.ListStyle.TitleBar.Render(m.List().FilterInput.View())
 	}
@@ -672,10 +993,11 @@ func (m *Model) View() string {
 }
 
 func (m *Model) saveState() {
-	s := State{
-		CurrentFolder:  string(m.selectedFolder()),
-		CurrentSnippet: m.selectedSnippet().File,
-	}
+	s := readState()
+	// keep the usage recorded by the commands run while nap was open
+	m.usage.merge(s.Usage, m.started)
+	s.Usage = m.usage
+	s.setSession(m.session, m.currentSession())
 	err := s.Save()
 	if err != nil {
 		panic(err.Error())
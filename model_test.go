@@ -0,0 +1,605 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRename(t *testing.T) {
+	setup := func(t *testing.T) *Model {
+		t.Helper()
+		tmp := tmpHome(t)
+		for name, content := range map[string]string{"a.go": "a", "b.go": "b"} {
+			if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+				t.Logf("could not create folder: %v", err)
+				t.FailNow()
+			}
+			if err := os.WriteFile(filepath.Join(tmp, "foo", name), []byte(content), 0o644); err != nil {
+				t.Logf("could not create snippet: %v", err)
+				t.FailNow()
+			}
+		}
+		cfg := readConfig()
+		return newModel(cfg, scanSnippets(cfg, nil), State{})
+	}
+	rename := func(s Snippet, folder, name string) Snippet {
+		s.Folder = folder
+		s.Name = name
+		s.File = name + "." + s.Language
+		return s
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		m := setup(t)
+		a, _ := m.snippetAt(filepath.Join("foo", "a.go"))
+		op := &renameOp{from: a, to: rename(a, "foo", "b")}
+		m.submitRename(op)
+		if m.pendingRename != op {
+			t.Log("rename onto an existing snippet should wait for conflict resolution")
+			t.FailNow()
+		}
+		if content := readFile(t, m, "foo/b.go"); content != "b" {
+			t.Logf("existing snippet was modified before resolution: got %q", content)
+			t.FailNow()
+		}
+	})
+
+	t.Run("overwrite and undo", func(t *testing.T) {
+		m := setup(t)
+		a, _ := m.snippetAt(filepath.Join("foo", "a.go"))
+		a.Tags = []string{"keep"}
+		m.replaceSnippet(a, a)
+		m.commitRename(&renameOp{from: a, to: rename(a, "foo", "b")}, true)
+		if n := len(m.Lists["foo"].Items()); n != 1 {
+			t.Logf("overwritten snippet should be removed from the list: got %d items", n)
+			t.FailNow()
+		}
+		if content := readFile(t, m, "foo/b.go"); content != "a" {
+			t.Logf("snippet was not overwritten: got %q", content)
+			t.FailNow()
+		}
+		if b, _ := m.snippetAt(filepath.Join("foo", "b.go")); len(b.Tags) != 1 {
+			t.Log("renamed snippet lost its tags")
+			t.FailNow()
+		}
+
+		m.undo()
+		if content := readFile(t, m, "foo/a.go"); content != "a" {
+			t.Logf("undo did not restore the renamed snippet: got %q", content)
+			t.FailNow()
+		}
+		if content := readFile(t, m, "foo/b.go"); content != "b" {
+			t.Logf("undo did not restore the overwritten snippet: got %q", content)
+			t.FailNow()
+		}
+		if n := len(m.Lists["foo"].Items()); n != 2 {
+			t.Logf("undo should restore both snippets: got %d items", n)
+			t.FailNow()
+		}
+
+		m.redo()
+		if content := readFile(t, m, "foo/b.go"); content != "a" {
+			t.Logf("redo did not overwrite the snippet again: got %q", content)
+			t.FailNow()
+		}
+	})
+
+	t.Run("overwrite file and undo", func(t *testing.T) {
+		m := setup(t)
+		// a file no snippet is listed for yet
+		if err := os.WriteFile(filepath.Join(m.config.Home, "foo", "c.go"), []byte("c"), 0o644); err != nil {
+			t.Logf("could not create file: %v", err)
+			t.FailNow()
+		}
+		a, _ := m.snippetAt(filepath.Join("foo", "a.go"))
+		m.commitRename(&renameOp{from: a, to: rename(a, "foo", "c")}, true)
+		m.undo()
+		if content := readFile(t, m, "foo/c.go"); content != "c" {
+			t.Logf("undo did not restore the overwritten file: got %q", content)
+			t.FailNow()
+		}
+	})
+
+	t.Run("undo keeps positions", func(t *testing.T) {
+		m := setup(t)
+		b, _ := m.snippetAt(filepath.Join("foo", "b.go"))
+		_, before, _ := m.locateSnippet(b.Path())
+		m.commitRename(&renameOp{from: b, to: rename(b, "bar", "b")}, false)
+		m.undo()
+		if _, after, ok := m.locateSnippet(b.Path()); !ok || after != before {
+			t.Logf("moved snippet should be restored at index %d, got %d", before, after)
+			t.FailNow()
+		}
+
+		a, _ := m.snippetAt(filepath.Join("foo", "a.go"))
+		_, before, _ = m.locateSnippet(b.Path())
+		m.commitRename(&renameOp{from: a, to: rename(a, "foo", "b")}, true)
+		m.undo()
+		if _, after, ok := m.locateSnippet(b.Path()); !ok || after != before {
+			t.Logf("overwritten snippet should be restored at index %d, got %d", before, after)
+			t.FailNow()
+		}
+	})
+
+	t.Run("suffix", func(t *testing.T) {
+		m := setup(t)
+		a, _ := m.snippetAt(filepath.Join("foo", "a.go"))
+		to := m.uniqueSnippet(rename(a, "foo", "b"))
+		if to.File != "b-1.go" {
+			t.Logf("unexpected suffixed name: got %q", to.File)
+			t.FailNow()
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		m := setup(t)
+		a, _ := m.snippetAt(filepath.Join("foo", "a.go"))
+		a.Date = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		m.replaceSnippet(a, a)
+		m.commitRename(&renameOp{from: a, to: rename(a, "bar", "a")}, false)
+		moved, ok := m.snippetAt(filepath.Join("bar", "a.go"))
+		if !ok {
+			t.Log("moved snippet is missing from the new folder's list")
+			t.FailNow()
+		}
+		if !moved.Date.Equal(a.Date) {
+			t.Log("moved snippet lost its metadata")
+			t.FailNow()
+		}
+		if n := len(m.Lists["foo"].Items()); n != 1 {
+			t.Logf("moved snippet is still in the old folder's list: got %d items", n)
+			t.FailNow()
+		}
+	})
+}
+
+func TestUndoDelete(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "a.go"), []byte("a"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+
+	if err := m.deleteSnippet(m.selectedSnippet()); err != nil {
+		t.Logf("could not delete snippet: %v", err)
+		t.FailNow()
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "foo", "a.go")); err == nil {
+		t.Log("deleted snippet file still exists")
+		t.FailNow()
+	}
+
+	m.undo()
+	if content := readFile(t, m, "foo/a.go"); content != "a" {
+		t.Logf("undo did not restore the deleted snippet: got %q", content)
+		t.FailNow()
+	}
+	if n := len(m.Lists["foo"].Items()); n != 1 {
+		t.Logf("undo did not restore the list item: got %d items", n)
+		t.FailNow()
+	}
+}
+
+func TestBulkActions(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		if err := os.WriteFile(filepath.Join(tmp, "foo", name), []byte(name), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	cfg := readConfig()
+	// the marked snippets are listed in the Recent and Frequent folders too
+	usage := Usage{}
+	for _, name := range []string{"a.go", "b.go"} {
+		usage.record(filepath.Join("foo", name), usageCopy)
+	}
+	m := newModel(cfg, scanSnippets(cfg, nil), State{Usage: usage})
+
+	m.toggleVisual()
+	m.List().Select(1)
+	m.updateVisual()
+	if n := len(m.targetSnippets()); n != 2 {
+		t.Logf("range should mark 2 snippets once: got %d", n)
+		t.FailNow()
+	}
+
+	if err := m.tagSnippets(m.targetSnippets(), "x y -x"); err != nil {
+		t.Logf("could not tag snippets: %v", err)
+		t.FailNow()
+	}
+	for _, s := range m.targetSnippets() {
+		if len(s.Tags) != 1 || s.Tags[0] != "y" {
+			t.Logf("unexpected tags for %s: %v", s, s.Tags)
+			t.FailNow()
+		}
+	}
+
+	if err := m.moveSnippets(m.targetSnippets(), "bar"); err != nil {
+		t.Logf("could not move snippets: %v", err)
+		t.FailNow()
+	}
+	if n := len(m.Lists["bar"].Items()); n != 2 {
+		t.Logf("snippets were not moved: got %d items in the new folder", n)
+		t.FailNow()
+	}
+	if m.hasMarks() {
+		t.Log("marks should be cleared after moving")
+		t.FailNow()
+	}
+
+	m.undo()
+	if n := len(m.Lists["foo"].Items()); n != 3 {
+		t.Logf("undo should move all snippets back at once: got %d items", n)
+		t.FailNow()
+	}
+
+	// space and f page through the other panes
+	for _, p := range []pane{snippetPane, contentPane, folderPane} {
+		m.pane = p
+		m.updateKeyMap()
+		if mark, fav := m.keys.MarkSnippet.Enabled(), m.keys.FavoriteSnippet.Enabled(); mark != (p == snippetPane) || fav != (p == snippetPane) {
+			t.Logf("marking and favoriting should only be enabled in the snippet pane, got %v and %v in pane %d", mark, fav, p)
+			t.FailNow()
+		}
+	}
+}
+
+func TestFolders(t *testing.T) {
+	setup := func(t *testing.T) *Model {
+		t.Helper()
+		tmp := tmpHome(t)
+		for _, path := range []string{"foo/a.go", "foo/b.go", "bar/a.go"} {
+			if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+				t.Logf("could not create folder: %v", err)
+				t.FailNow()
+			}
+			if err := os.WriteFile(filepath.Join(tmp, path), []byte(path), 0o644); err != nil {
+				t.Logf("could not create snippet: %v", err)
+				t.FailNow()
+			}
+		}
+		cfg := readConfig()
+		return newModel(cfg, scanSnippets(cfg, nil), State{})
+	}
+
+	t.Run("create", func(t *testing.T) {
+		m := setup(t)
+		if err := m.createFolder("baz"); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if m.selectedFolder() != "baz" {
+			t.Logf("new folder should be selected: got %q", m.selectedFolder())
+			t.FailNow()
+		}
+		if err := m.createFolder("foo"); err == nil {
+			t.Log("creating an existing folder should fail")
+			t.FailNow()
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		m := setup(t)
+		m.usage.record(filepath.Join("foo", "b.go"), usageCopy)
+		if err := m.do(&folderRenameOp{from: "foo", to: "qux"}); err != nil {
+			t.Logf("could not rename folder: %v", err)
+			t.FailNow()
+		}
+		if content := readFile(t, m, "qux/b.go"); content != "foo/b.go" {
+			t.Logf("snippet was not moved with its folder: got %q", content)
+			t.FailNow()
+		}
+		for _, item := range m.Lists["qux"].Items() {
+			if s := item.(Snippet); s.Folder != "qux" {
+				t.Logf("snippet folder was not updated: got %q", s.Folder)
+				t.FailNow()
+			}
+		}
+		if m.usage.count(filepath.Join("qux", "b.go")) != 1 || m.usage.count(filepath.Join("foo", "b.go")) != 0 {
+			t.Logf("usage should follow the folder: got %v", m.usage)
+			t.FailNow()
+		}
+		if err := m.do(&folderRenameOp{from: "qux", to: "bar"}); err == nil {
+			t.Log("renaming onto an existing folder should fail")
+			t.FailNow()
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		m := setup(t)
+		if err := m.deleteFolder("foo"); err != nil {
+			t.Logf("could not delete folder: %v", err)
+			t.FailNow()
+		}
+		if _, ok := m.Lists["foo"]; ok {
+			t.Log("deleted folder is still listed")
+			t.FailNow()
+		}
+		if n := len(m.Lists[trashFolder].Items()); n != 2 {
+			t.Logf("folder snippets should be in the trash: got %d", n)
+			t.FailNow()
+		}
+		m.undo()
+		if n := len(m.Lists["foo"].Items()); n != 2 {
+			t.Logf("undo should restore the folder snippets: got %d", n)
+			t.FailNow()
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		m := setup(t)
+		if err := m.mergeFolder("foo", "bar"); err != nil {
+			t.Logf("could not merge folders: %v", err)
+			t.FailNow()
+		}
+		if n := len(m.Lists["bar"].Items()); n != 3 {
+			t.Logf("merged folder should hold all snippets: got %d", n)
+			t.FailNow()
+		}
+		if content := readFile(t, m, "bar/a-1.go"); content != "foo/a.go" {
+			t.Logf("conflicting snippet should be suffixed: got %q", content)
+			t.FailNow()
+		}
+	})
+
+	t.Run("order", func(t *testing.T) {
+		m := setup(t)
+		m.selectFolder("foo")
+		m.moveFolder(-1)
+		if order := m.snippetFolders(); order[0] != "foo" || order[1] != "bar" {
+			t.Logf("folder was not moved up: got %v", order)
+			t.FailNow()
+		}
+		if err := writeFolderOrder(m.config, m.snippetFolders()); err != nil {
+			t.Logf("could not save folder order: %v", err)
+			t.FailNow()
+		}
+		m = newModel(m.config, scanSnippets(m.config, nil), State{})
+		if order := m.snippetFolders(); order[0] != "foo" {
+			t.Logf("folder order was not restored: got %v", order)
+			t.FailNow()
+		}
+	})
+}
+
+func TestSort(t *testing.T) {
+	tmp := tmpHome(t)
+	for _, path := range []string{"foo/b.go", "foo/c.go", "foo/a.go"} {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(path), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.selectFolder("foo")
+
+	names := func() string {
+		var names []string
+		for _, s := range listSnippetsOf(m.Lists["foo"]) {
+			names = append(names, s.Name)
+		}
+		return strings.Join(names, "")
+	}
+
+	m.Lists["foo"].Select(0)
+	m.moveSnippet(-1)
+	m.Lists["foo"].Select(2)
+	m.moveSnippet(3)
+	if got := names(); got != "abc" {
+		t.Logf("moves past the list bounds should be ignored: got %q", got)
+		t.FailNow()
+	}
+
+	m.moveSnippet(0)
+	if got := names(); got != "cab" {
+		t.Logf("snippet was not moved to the top: got %q", got)
+		t.FailNow()
+	}
+
+	m.cycleSort()
+	if m.sort != sortName || names() != "abc" {
+		t.Logf("snippets should be sorted by name: got %s %q", m.sort, names())
+		t.FailNow()
+	}
+
+	var saved []string
+	for _, s := range m.allSnippets() {
+		saved = append(saved, s.Name)
+	}
+	if got := strings.Join(saved, ""); got != "cab" {
+		t.Logf("manual order should be saved regardless of the sort mode: got %q", got)
+		t.FailNow()
+	}
+}
+
+func TestUndoKey(t *testing.T) {
+	lists, content := list.DefaultKeyMap(), viewport.DefaultKeyMap()
+	navigation := []key.Binding{
+		lists.CursorUp, lists.CursorDown, lists.PrevPage, lists.NextPage, lists.GoToStart, lists.GoToEnd,
+		content.PageDown, content.PageUp, content.HalfPageUp, content.HalfPageDown, content.Up, content.Down,
+	}
+	for _, k := range DefaultKeyMap.Undo.Keys() {
+		for _, b := range navigation {
+			if key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}, b) {
+				t.Logf("undo key %q takes over navigation key %v", k, b.Keys())
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestUndoMoveAfterChanges(t *testing.T) {
+	tmp := tmpHome(t)
+	for _, path := range []string{"foo/a.go", "foo/b.go", "foo/c.go"} {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(path), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.selectFolder("foo")
+	names := func() string {
+		var names []string
+		for _, s := range listSnippetsOf(m.Lists["foo"]) {
+			names = append(names, s.Name)
+		}
+		return strings.Join(names, "")
+	}
+
+	m.Lists["foo"].Select(2)
+	m.moveSnippet(0)
+	m.Lists["foo"].Select(2)
+	m.moveSnippet(1)
+	if got := names(); got != "cba" {
+		t.Logf("snippets were not moved: got %q", got)
+		t.FailNow()
+	}
+
+	// the list changed behind the history, by a reload or a delete
+	m.removeSnippet(filepath.Join("foo", "c.go"))
+	m.undo()
+	if got := names(); got != "ba" || len(m.history.done) != 1 {
+		t.Logf("undoing a move past the end of the list should be dropped: got %q and %d operations", got, len(m.history.done))
+		t.FailNow()
+	}
+	m.undo()
+	if got := names(); got != "ba" || len(m.history.done) != 0 || len(m.history.undone) != 0 {
+		t.Logf("undoing the move of a removed snippet should be dropped: got %q", got)
+		t.FailNow()
+	}
+
+	m.Lists["foo"].Select(1)
+	m.moveSnippet(0)
+	m.insertSnippet(0, Snippet{Folder: "foo", Name: "d", File: "d.go", Language: "go"})
+	m.undo()
+	if got := names(); got != "dab" {
+		t.Logf("the moved snippet, rather than the one at its index, should be moved back: got %q", got)
+		t.FailNow()
+	}
+}
+
+func TestSession(t *testing.T) {
+	tmp := tmpHome(t)
+	for _, path := range []string{"foo/a.go", "foo/b.go", "bar/c.go"} {
+		if err := os.MkdirAll(filepath.Join(tmp, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Logf("could not create folder: %v", err)
+			t.FailNow()
+		}
+		if err := os.WriteFile(filepath.Join(tmp, path), []byte(path), 0o644); err != nil {
+			t.Logf("could not create snippet: %v", err)
+			t.FailNow()
+		}
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.selectFolder("foo")
+	m.Lists["foo"].Select(1)
+	m.cycleSort()
+	m.pane = contentPane
+	m.help.ShowAll = true
+
+	b := m.selectedSnippet()
+	renamed := b
+	renamed.Name = "renamed"
+	renamed.File = "renamed.go"
+	if err := m.do(&renameOp{from: b, to: renamed}); err != nil {
+		t.Logf("could not rename snippet: %v", err)
+		t.FailNow()
+	}
+	m.saveState()
+
+	m.session = "work"
+	m.selectFolder("bar")
+	m.saveState()
+
+	state := readState()
+	m = newModel(cfg, m.allSnippets(), state)
+	if m.selectedFolder() != "foo" || m.selectedSnippet().Name != "renamed" {
+		t.Logf("selection was not restored across the rename: got %s", m.selectedSnippet())
+		t.FailNow()
+	}
+	if m.sort != sortName || m.pane != contentPane || !m.help.ShowAll {
+		t.Logf("session was not restored: got sort %s, pane %d, help %t", m.sort, m.pane, m.help.ShowAll)
+		t.FailNow()
+	}
+
+	state.Session = state.session("work")
+	m = newModel(cfg, m.allSnippets(), state)
+	if m.selectedFolder() != "bar" {
+		t.Logf("named session was not restored: got folder %q", m.selectedFolder())
+		t.FailNow()
+	}
+
+	state.Session = Session{CurrentFolder: "foo", Filter: "renamed"}
+	m = newModel(cfg, m.allSnippets(), state)
+	if items := m.List().VisibleItems(); m.List().FilterState() != list.FilterApplied || len(items) != 1 {
+		t.Logf("filter was not restored: got %d items", len(items))
+		t.FailNow()
+	}
+}
+
+func readFile(t *testing.T, m *Model, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(m.config.Home, path))
+	if err != nil {
+		t.Logf("could not read %s: %v", path, err)
+		t.FailNow()
+	}
+	return string(b)
+}
+
+func TestPaste(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "a.go"), []byte("a\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	if err := m.pasteSnippet(m.selectedSnippet(), "b\n"); err != nil {
+		t.Logf("could not paste: %v", err)
+		t.FailNow()
+	}
+	if got := m.selectedSnippet().Hash; readFile(t, m, "foo/a.go") != "a\nb\n" || got != contentHash([]byte("a\nb\n")) {
+		t.Logf("expected the content to be pasted and hashed, got hash %q", got)
+		t.FailNow()
+	}
+	m.undo()
+	if got := m.selectedSnippet().Hash; readFile(t, m, "foo/a.go") != "a\n" || got != contentHash([]byte("a\n")) {
+		t.Logf("expected undo to restore the content and its hash, got hash %q", got)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/slices"
+)
+
+// pickerHeight is the number of snippets shown by the picker.
+const pickerHeight = 10
+
+// picker is a compact snippet picker drawn inline below the shell prompt.
+type picker struct {
+	snippets []Snippet
+	usage    Usage
+	input    textinput.Model
+	// matches are the snippets matching the query, best first.
+	matches []Snippet
+	cursor  int
+	// picked is set once the user chose a snippet.
+	picked *Snippet
+	done   bool
+
+	selected   lipgloss.Style
+	unselected lipgloss.Style
+}
+
+// newPicker returns a picker for the snippets, the most used first.
+func newPicker(config Config, snippets []Snippet, usage Usage) *picker {
+	renderer := lipgloss.NewRenderer(os.Stderr)
+	input := newTextInput("Search snippets")
+	input.Prompt = "> "
+	input.Focus()
+	p := &picker{
+		snippets:   snippets,
+		usage:      usage,
+		input:      input,
+		selected:   renderer.NewStyle().Foreground(lipgloss.Color(config.PrimaryColor)),
+		unselected: renderer.NewStyle().Foreground(lipgloss.Color(config.SubTextColor)),
+	}
+	p.filter()
+	return p
+}
+
+// filter updates the matches for the current query.
+func (p *picker) filter() {
+	p.cursor = 0
+	query := p.input.Value()
+	if query == "" {
+		p.matches = slices.Clone(p.snippets)
+		slices.SortStableFunc(p.matches, func(a, b Snippet) int {
+			return p.usage.frecency(b.Path()) - p.usage.frecency(a.Path())
+		})
+		return
+	}
+	matches := fuzzy.FindFrom(query, Snippets{p.snippets})
+	slices.SortStableFunc(matches, func(a, b fuzzy.Match) int {
+		return rank(b, p.snippets, p.usage) - rank(a, p.snippets, p.usage)
+	})
+	p.matches = p.matches[:0]
+	for _, match := range matches {
+		p.matches = append(p.matches, p.snippets[match.Index])
+	}
+}
+
+// Init initializes the picker.
+func (p *picker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles the keys of the picker.
+func (p *picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, pickerKeys.Pick):
+			if len(p.matches) > 0 {
+				p.picked = &p.matches[p.cursor]
+			}
+			p.done = true
+			return p, tea.Quit
+		case key.Matches(msg, pickerKeys.Cancel):
+			p.done = true
+			return p, tea.Quit
+		case key.Matches(msg, pickerKeys.Up):
+			if p.cursor > 0 {
+				p.cursor--
+			}
+			return p, nil
+		case key.Matches(msg, pickerKeys.Down):
+			if p.cursor < len(p.matches)-1 {
+				p.cursor++
+			}
+			return p, nil
+		}
+	}
+
+	query := p.input.Value()
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != query {
+		p.filter()
+	}
+	return p, cmd
+}
+
+// View renders the query and the best matches, scrolled to the cursor.
+func (p *picker) View() string {
+	if p.done {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(p.input.View())
+	start := 0
+	if p.cursor >= pickerHeight {
+		start = p.cursor - pickerHeight + 1
+	}
+	for i := start; i < len(p.matches) && i < start+pickerHeight; i++ {
+		b.WriteString("\n")
+		if i == p.cursor {
+			b.WriteString(p.selected.Render("→ " + p.matches[i].String()))
+		} else {
+			b.WriteString(p.unselected.Render("  " + p.matches[i].String()))
+		}
+	}
+	fmt.Fprintf(&b, "\n%s", p.unselected.Render(fmt.Sprintf("  %d/%d", len(p.matches), len(p.snippets))))
+	return b.String()
+}
+
+// pickerKeys are the key bindings of the picker.
+var pickerKeys = struct {
+	Pick   key.Binding
+	Cancel key.Binding
+	Up     key.Binding
+	Down   key.Binding
+}{
+	Pick:   key.NewBinding(key.WithKeys("enter")),
+	Cancel: key.NewBinding(key.WithKeys("esc", "ctrl+c")),
+	Up:     key.NewBinding(key.WithKeys("up", "ctrl+p", "ctrl+k")),
+	Down:   key.NewBinding(key.WithKeys("down", "ctrl+n", "ctrl+j")),
+}
+
+// runPicker runs the `nap pick` subcommand, printing the content of the
+// chosen snippet to stdout. The picker is drawn on stderr so that the output
+// can be captured by the shell widgets.
+func runPicker(config Config, snippets []Snippet) {
+	state := readState()
+	p := newPicker(config, snippets, state.Usage)
+	_, err := tea.NewProgram(p, tea.WithOutput(os.Stderr), tea.WithInputTTY()).Run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Alas, there's been an error", err)
+		return
+	}
+	if p.picked == nil {
+		return
+	}
+	fmt.Print(p.picked.Content(config, false))
+	recordUsage(*p.picked, usagePrint)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// renameOp records a snippet rename (or move to another folder) with enough
+// information to revert it.
+type renameOp struct {
+	from Snippet
+	to   Snippet
+	// index is the position of the snippet in its list before the rename.
+	index int
+	// overwrite is set when the user chose to replace the snippet or file
+	// that previously lived at the destination. The file content is kept in
+	// overwrittenContent, and the snippet, if any, in overwritten along with
+	// its position.
+	overwrite          bool
+	overwritten        *Snippet
+	overwrittenIndex   int
+	overwrittenFile    bool
+	overwrittenContent []byte
+}
+
+// submitRename renames the snippet described by op, or asks the user how to
+// resolve the conflict if another snippet already lives at the destination.
+func (m *Model) submitRename(op *renameOp) tea.Cmd {
+	if op.to.Path() != op.from.Path() && m.pathTaken(op.to.Path()) {
+		m.pendingRename = op
+		return changeState(conflictingState)
+	}
+	return m.commitRename(op, false)
+}
+
+// commitRename performs the rename and refreshes the folders and content
+// views to follow the snippet to its new location.
+func (m *Model) commitRename(op *renameOp, overwrite bool) tea.Cmd {
+	op.overwrite = overwrite
+	if err := m.renameSnippet(op, overwrite); err != nil {
+		m.displayError(fmt.Sprintf("Unable to rename snippet: %v", err))
+		return nil
+	}
+	m.history.push(op)
+	m.updateUsageViews()
+	m.pane = snippetPane
+	return tea.Batch(m.updateFolders(Folder(op.to.Folder)), func() tea.Msg {
+		return updateContentMsg(op.to)
+	})
+}
+
+// renameSnippet moves the snippet file from op.from to op.to and updates the
+// lists accordingly. When overwrite is set, any snippet at the destination is
+// replaced and remembered in op so that it can be restored.
+func (m *Model) renameSnippet(op *renameOp, overwrite bool) error {
+	oldPath := filepath.Join(m.config.Home, op.from.Path())
+	newPath := filepath.Join(m.config.Home, op.to.Path())
+	if err := os.MkdirAll(filepath.Dir(newPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	if oldPath != newPath && overwrite {
+		content, err := os.ReadFile(newPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		op.overwrittenFile, op.overwrittenContent = err == nil, content
+		op.overwritten = nil
+		if folder, idx, ok := m.locateSnippet(op.to.Path()); ok {
+			existing := m.Lists[folder].Items()[idx].(Snippet)
+			op.overwritten, op.overwrittenIndex = &existing, idx
+			m.removeSnippet(existing.Path())
+		}
+	}
+	_, op.index, _ = m.locateSnippet(op.from.Path())
+
+	if oldPath != newPath {
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+	}
+	m.replaceSnippet(op.from, op.to)
+	return nil
+}
+
+// uniqueSnippet returns a copy of the snippet with a numeric suffix appended to
+// its name so that it does not collide with any existing snippet.
+func (m *Model) uniqueSnippet(s Snippet) Snippet {
+	return uniqueSnippet(s, m.pathTaken)
+}
+
+// uniqueSnippet returns a copy of the snippet with a numeric suffix appended to
+// its name until its path is no longer taken.
+func uniqueSnippet(s Snippet, taken func(path string) bool) Snippet {
+	name := s.Name
+	for i := 1; taken(s.Path()); i++ {
+		s.Name = fmt.Sprintf("%s-%d", name, i)
+		s.File = s.fileName()
+	}
+	return s
+}
+
+// pathTaken reports whether a snippet or file already exists at the given
+// snippet path.
+func (m *Model) pathTaken(path string) bool {
+	if _, ok := m.snippetAt(path); ok {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(m.config.Home, path))
+	return err == nil
+}
+
+// snippetAt returns the snippet stored at the given path.
+func (m *Model) snippetAt(path string) (Snippet, bool) {
+	folder, idx, ok := m.locateSnippet(path)
+	if !ok {
+		return Snippet{}, false
+	}
+	return m.Lists[folder].Items()[idx].(Snippet), true
+}
+
+// locateSnippet returns the folder and list index of the snippet stored at the
+// given path.
+func (m *Model) locateSnippet(path string) (Folder, int, bool) {
+	for folder, li := range m.Lists {
+		for i, item := range li.Items() {
+			// skip the Recent and Frequent folders listing snippets of
+			// other folders
+			if s, ok := item.(Snippet); ok && s.Path() == path && Folder(s.Folder) == folder {
+				return folder, i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// removeSnippet removes the snippet stored at path from its list.
+func (m *Model) removeSnippet(path string) {
+	folder, idx, ok := m.locateSnippet(path)
+	if !ok {
+		return
+	}
+	m.Lists[folder].RemoveItem(idx)
+}
+
+// insertSnippet inserts the snippet into its folder's list at the given index,
+// creating the list if the folder is new.
+func (m *Model) insertSnippet(idx int, s Snippet) {
+	folder := Folder(s.Folder)
+	if _, ok := m.Lists[folder]; !ok {
+		m.Lists[folder] = newList([]list.Item{}, m.height, m.ListStyle)
+	}
+	m.Lists[folder].InsertItem(idx, s)
+}
+
+// replaceSnippet replaces the old snippet with the new one. The snippet keeps
+// its position if it stays in the same folder, otherwise it is moved to the top
+// of the new folder's list and selected there.
+func (m *Model) replaceSnippet(old, new Snippet) {
+	m.usage.rename(old.Path(), new.Path())
+	folder, idx, ok := m.locateSnippet(old.Path())
+	if ok && folder == Folder(new.Folder) {
+		m.Lists[folder].SetItem(idx, new)
+		return
+	}
+	if ok {
+		m.Lists[folder].RemoveItem(idx)
+	}
+	if li, ok := m.Lists[Folder(new.Folder)]; ok {
+		lo, _ := folderOrderBounds(listSnippetsOf(li), new.Folder)
+		new.Order = lo - 1
+	}
+	m.insertSnippet(0, new)
+	m.Lists[Folder(new.Folder)].Select(0)
+}
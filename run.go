@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/exp/slices"
+)
+
+// defaultRunners are the commands running snippets of each language. The
+// {file} placeholder is replaced with the path of the snippet file, which is
+// otherwise appended to the command.
+var defaultRunners = map[string]string{
+	"sh":   "sh {file}",
+	"bash": "bash {file}",
+	"zsh":  "zsh {file}",
+	"fish": "fish {file}",
+	"py":   "python3 {file}",
+	"go":   "go run {file}",
+	"js":   "node {file}",
+	"ts":   "deno run {file}",
+	"rb":   "ruby {file}",
+	"pl":   "perl {file}",
+	"lua":  "lua {file}",
+	"php":  "php {file}",
+}
+
+// placeholderPattern matches the {{name}} placeholders of a snippet.
+var placeholderPattern = regexp.MustCompile(`{{\s*(\w+)\s*}}`)
+
+// placeholders returns the names of the placeholders of the content, in the
+// order they first appear.
+func placeholders(content string) []string {
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// expandPlaceholders replaces the placeholders of the content with the
+// arguments. Arguments of the form name=value set the named placeholder and
+// the others fill the remaining placeholders in order. The arguments left
+// over are returned to be passed to the snippet.
+func expandPlaceholders(content string, args []string) (string, []string, error) {
+	names := placeholders(content)
+	if len(names) == 0 {
+		return content, args, nil
+	}
+
+	values := map[string]string{}
+	var rest []string
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && slices.Contains(names, name) {
+			values[name] = value
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	for _, name := range names {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if len(rest) == 0 {
+			return content, nil, fmt.Errorf("missing value for placeholder %q", name)
+		}
+		values[name] = rest[0]
+		rest = rest[1:]
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(content, func(s string) string {
+		return values[placeholderPattern.FindStringSubmatch(s)[1]]
+	}), rest, nil
+}
+
+// runnerCmd returns the command running the snippet with the arguments. The
+// snippet is run from a temporary copy with its placeholders expanded, which
+// is removed by cleanup once the command is done.
+func runnerCmd(config Config, s Snippet, args []string) (*exec.Cmd, func(), error) {
+	if s.Multi {
+		return nil, nil, fmt.Errorf("%s has several files, write them out with nap materialize to run them", s)
+	}
+	runner, ok := config.Runners[s.Language]
+	if !ok || strings.TrimSpace(runner) == "" {
+		return nil, nil, fmt.Errorf("no runner for %q snippets", s.Language)
+	}
+
+	content, err := os.ReadFile(filepath.Join(config.Home, s.Path()))
+	if err != nil {
+		return nil, nil, err
+	}
+	expanded, args, err := expandPlaceholders(string(content), args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "nap-run-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	file := filepath.Join(dir, s.File)
+	if err := os.WriteFile(file, []byte(expanded), 0o755); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	fields, err := splitCommand(runner)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("runner for %q snippets: %w", s.Language, err)
+	}
+	if !strings.Contains(runner, "{file}") {
+		fields = append(fields, file)
+	}
+	for i := range fields {
+		fields[i] = strings.ReplaceAll(fields[i], "{file}", file)
+	}
+	return exec.Command(fields[0], append(fields[1:], args...)...), cleanup, nil
+}
+
+// splitCommand splits the command into its arguments like a shell would,
+// at spaces outside of single or double quotes. A backslash escapes the
+// next character, except within single quotes.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", command)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
+
+// exitStatus returns the exit status of a command that finished with err.
+func exitStatus(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+// runCommand runs the `nap run` subcommand and returns the exit status of the
+// snippet.
+func runCommand(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	yes := flags.Bool("y", false, "run without asking for confirmation")
+	exact := flags.Bool("exact", false, "only run the snippet at the exact folder/name.ext")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) == 0 {
+		fmt.Println("usage: nap run [-y] [--exact] <snippet> [-- args]")
+		return 2
+	}
+	snippet, ok := lookupSnippet(args[0], snippets, *exact)
+	if !ok {
+		return 1
+	}
+	args = args[1:]
+
+	if !*yes {
+		if !isatty.IsTerminal(os.Stdin.Fd()) {
+			fmt.Println("refusing to run a snippet without confirmation, use -y")
+			return 1
+		}
+		fmt.Printf("Run %s? (y/N) ", snippet)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			return 1
+		}
+	}
+
+	cmd, cleanup, err := runnerCmd(config, snippet, args)
+	if err != nil {
+		fmt.Printf("could not run %s: %v\n", snippet, err)
+		return 1
+	}
+	defer cleanup()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	recordUsage(snippet, usageRun)
+	status := exitStatus(cmd.Run())
+	if status != 0 {
+		fmt.Printf("%s exited with status %d\n", snippet, status)
+	}
+	return status
+}
+
+// run is a snippet running from the TUI, whose output is shown in place of
+// the snippet content.
+type run struct {
+	snippet Snippet
+	cmd     *exec.Cmd
+	// msgs streams the output of the command, followed by its exit.
+	msgs   chan tea.Msg
+	output strings.Builder
+	done   bool
+	status int
+}
+
+// runOutputMsg is output written by a running snippet.
+type runOutputMsg struct {
+	run    *run
+	output string
+}
+
+// runDoneMsg is sent once a running snippet exits.
+type runDoneMsg struct {
+	run *run
+	err error
+}
+
+// runWriter sends the output written to it as runOutputMsgs.
+type runWriter struct{ run *run }
+
+func (w runWriter) Write(p []byte) (int, error) {
+	w.run.msgs <- runOutputMsg{w.run, string(p)}
+	return len(p), nil
+}
+
+// runSnippet starts running the snippet with the arguments and returns a Cmd
+// streaming its output to the results pane.
+func (m *Model) runSnippet(s Snippet, args []string) tea.Cmd {
+	m.closeRun()
+	cmd, cleanup, err := runnerCmd(m.config, s, args)
+	if err != nil {
+		m.displayError(fmt.Sprintf("Unable to run snippet: %v", err))
+		return nil
+	}
+	r := &run{snippet: s, cmd: cmd, msgs: make(chan tea.Msg)}
+	cmd.Stdout = runWriter{r}
+	cmd.Stderr = runWriter{r}
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		m.displayError(fmt.Sprintf("Unable to run snippet: %v", err))
+		return nil
+	}
+	go func() {
+		err := cmd.Wait()
+		cleanup()
+		r.msgs <- runDoneMsg{r, err}
+		close(r.msgs)
+	}()
+	m.run = r
+	m.recordUsage([]Snippet{s}, usageRun)
+	m.showRun()
+	return r.wait
+}
+
+// wait returns the next output or the exit of the running snippet.
+func (r *run) wait() tea.Msg {
+	return <-r.msgs
+}
+
+// closeRun closes the results pane, killing the snippet if it is still
+// running.
+func (m *Model) closeRun() {
+	r := m.run
+	if r == nil {
+		return
+	}
+	m.run = nil
+	if !r.done {
+		_ = r.cmd.Process.Kill()
+		go func() {
+			for range r.msgs {
+			}
+		}()
+	}
+}
+
+// showRun displays the output of the running snippet in the content pane.
+func (m *Model) showRun() {
+	m.LineNumbers.SetContent("")
+	m.Code.SetContent(m.run.output.String())
+	m.Code.GotoBottom()
+}
+
+// runStatus describes the state of the running snippet.
+func (m *Model) runStatus() string {
+	if !m.run.done {
+		return "running"
+	}
+	return fmt.Sprintf("exit %d", m.run.status)
+}
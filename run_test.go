@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
+)
+
+func TestExpandPlaceholders(t *testing.T) {
+	tt := []struct {
+		Name    string
+		Content string
+		Args    []string
+		Want    string
+		Rest    []string
+		Err     bool
+	}{
+		{
+			Name:    "no placeholders",
+			Content: "echo hi",
+			Args:    []string{"a"},
+			Want:    "echo hi",
+			Rest:    []string{"a"},
+		},
+		{
+			Name:    "positional",
+			Content: "echo {{greeting}} {{ name }} {{greeting}}",
+			Args:    []string{"hello", "world", "extra"},
+			Want:    "echo hello world hello",
+			Rest:    []string{"extra"},
+		},
+		{
+			Name:    "named",
+			Content: "echo {{greeting}} {{name}}",
+			Args:    []string{"name=world", "hello"},
+			Want:    "echo hello world",
+		},
+		{
+			Name:    "missing",
+			Content: "echo {{greeting}} {{name}}",
+			Args:    []string{"hello"},
+			Err:     true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, rest, err := expandPlaceholders(tc.Content, tc.Args)
+			if tc.Err {
+				if err == nil {
+					t.Log("expected an error for the missing placeholder")
+					t.FailNow()
+				}
+				return
+			}
+			if err != nil {
+				t.Logf("could not expand placeholders: %v", err)
+				t.FailNow()
+			}
+			if got != tc.Want {
+				t.Logf("content is incorrect: got %q but want %q", got, tc.Want)
+				t.FailNow()
+			}
+			if !slices.Equal(rest, tc.Rest) {
+				t.Logf("remaining args are incorrect: got %v but want %v", rest, tc.Rest)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet folder: %v", err)
+		t.FailNow()
+	}
+	script := "echo hello {{name}} \"$@\"\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "hello.sh"), []byte(script), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+
+	out := captureStdout(t, func() { runCLI([]string{"run", "-y", "foo/hello.sh", "--", "world", "again"}) })
+	if want := "hello world again\nfoo/hello.sh exited with status 3\n"; out != want {
+		t.Logf("output is incorrect: got %q but want %q", out, want)
+		t.FailNow()
+	}
+
+	out = captureStdout(t, func() { runCLI([]string{"run", "foo/hello.sh", "-y", "--", "-y", "--"}) })
+	if want := "hello -y --\nfoo/hello.sh exited with status 3\n"; out != want {
+		t.Logf("flags should be parsed up to --: got %q but want %q", out, want)
+		t.FailNow()
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Logf("could not open pipe: %v", err)
+		t.FailNow()
+	}
+	w.Close()
+	os.Stdin = r
+	out = captureStdout(t, func() { runCLI([]string{"run", "foo/hello.sh", "--", "world"}) })
+	if !strings.HasPrefix(out, "refusing to run") {
+		t.Logf("running without a terminal should require -y: got %q", out)
+		t.FailNow()
+	}
+
+	writeMultiSnippet(t, tmp)
+	var code int
+	out = captureStdout(t, func() { code = runCLI([]string{"run", "-y", "docker/stack"}) })
+	if code != 1 || !strings.Contains(out, "docker/stack has several files") {
+		t.Logf("running a multi-file snippet should be refused: exit %d, got %q", code, out)
+		t.FailNow()
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tt := []struct {
+		Command string
+		Want    []string
+		Err     bool
+	}{
+		{Command: "python3 {file}", Want: []string{"python3", "{file}"}},
+		{Command: "  sh  -c 'echo \"a b\"' {file}", Want: []string{"sh", "-c", `echo "a b"`, "{file}"}},
+		{Command: `node --title "my script" {file}`, Want: []string{"node", "--title", "my script", "{file}"}},
+		{Command: `run a\ b "c \"d\"" ''`, Want: []string{"run", "a b", `c "d"`, ""}},
+		{Command: "sh -c 'echo", Err: true},
+	}
+	for _, tc := range tt {
+		got, err := splitCommand(tc.Command)
+		if tc.Err {
+			if err == nil {
+				t.Logf("expected an error splitting %q, got %q", tc.Command, got)
+				t.FailNow()
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tc.Want) {
+			t.Logf("splitting %q: got %q, want %q: %v", tc.Command, got, tc.Want, err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestRunKilledOnQuit(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "foo"), os.ModePerm); err != nil {
+		t.Logf("could not create snippet folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "foo", "sleep.sh"), []byte("sleep 30\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	m.runSnippet(m.selectedSnippet(), nil)
+	r := m.run
+	if r == nil {
+		t.Log("expected the snippet to run")
+		t.FailNow()
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	deadline := time.Now().Add(5 * time.Second)
+	for r.cmd.Process.Signal(syscall.Signal(0)) == nil {
+		if time.Now().After(deadline) {
+			t.Log("expected the running snippet to be killed on quit")
+			t.FailNow()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runScan runs the `nap scan` subcommand, adding the snippets of the files
+// created in the home directory outside nap and removing those whose file is
+// gone or ignored, and returns the exit code. With --dry-run it only prints what would
+// be added or removed.
+func runScan(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print what would be added or removed without changing anything")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 0 {
+		fmt.Println("usage: nap scan [--dry-run]")
+		return 2
+	}
+
+	found, err := findNewSnippets(config, snippets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not scan config home: %v\n", err)
+	}
+	missing := findMissingSnippets(config, snippets)
+	added, removed := "added", "removed"
+	if *dryRun {
+		added, removed = "would add", "would remove"
+	}
+	for _, s := range found {
+		fmt.Printf("%s %s\n", added, s)
+	}
+	for _, s := range missing {
+		fmt.Printf("%s %s\n", removed, s)
+	}
+	if !*dryRun && (len(found) > 0 || len(missing) > 0) {
+		identifySnippets(config, scanSnippets(config, snippets))
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// selection holds the snippets marked for a bulk action, keyed by their path.
+type selection struct {
+	marked map[string]bool
+	// visual is set while the marks follow the cursor from anchor, on top of
+	// the snippets that were marked in base.
+	visual bool
+	anchor int
+	base   map[string]bool
+}
+
+// toggleMark marks or unmarks the selected snippet.
+func (m *Model) toggleMark() {
+	path := m.selectedSnippet().Path()
+	if m.selection.marked[path] {
+		delete(m.selection.marked, path)
+		return
+	}
+	if m.selection.marked == nil {
+		m.selection.marked = map[string]bool{}
+	}
+	m.selection.marked[path] = true
+}
+
+// toggleVisual starts or ends marking the range of snippets between the
+// current one and the cursor.
+func (m *Model) toggleVisual() {
+	if m.selection.visual {
+		m.selection.visual = false
+		return
+	}
+	m.selection.visual = true
+	m.selection.anchor = m.List().Index()
+	m.selection.base = maps.Clone(m.selection.marked)
+	m.updateVisual()
+}
+
+// updateVisual marks the snippets between the anchor and the cursor when in
+// visual mode.
+func (m *Model) updateVisual() {
+	if !m.selection.visual {
+		return
+	}
+	marked := maps.Clone(m.selection.base)
+	if marked == nil {
+		marked = map[string]bool{}
+	}
+	from, to := m.selection.anchor, m.List().Index()
+	if from > to {
+		from, to = to, from
+	}
+	items := m.List().VisibleItems()
+	for i := from; i <= to && i < len(items); i++ {
+		marked[items[i].(Snippet).Path()] = true
+	}
+	m.selection.marked = marked
+}
+
+// clearMarks unmarks all snippets.
+func (m *Model) clearMarks() {
+	m.selection = selection{}
+}
+
+// hasMarks reports whether any snippets are marked.
+func (m *Model) hasMarks() bool {
+	return len(m.selection.marked) > 0
+}
+
+// targetSnippets returns the snippets a bulk action applies to: the marked
+// snippets if there are any, or the selected snippet otherwise.
+func (m *Model) targetSnippets() []Snippet {
+	if !m.hasMarks() {
+		return []Snippet{m.selectedSnippet()}
+	}
+	var snippets []Snippet
+	// the Recent and Frequent folders list the same snippets again
+	for _, folder := range m.snippetFolders() {
+		for _, item := range m.Lists[folder].Items() {
+			if s, ok := item.(Snippet); ok && m.selection.marked[s.Path()] {
+				snippets = append(snippets, s)
+			}
+		}
+	}
+	return snippets
+}
+
+// deleteSnippets moves the snippets to the trash as a single operation.
+func (m *Model) deleteSnippets(snippets []Snippet) error {
+	if len(snippets) == 1 {
+		return m.deleteSnippet(snippets[0])
+	}
+	var ops []operation
+	defer func() { m.pushBatch("delete", ops) }()
+	for _, s := range snippets {
+		_, idx, _ := m.locateSnippet(s.Path())
+		op := &deleteOp{snippet: s, index: idx}
+		if err := op.redo(m); err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+	m.clearMarks()
+	return nil
+}
+
+// moveSnippets moves the snippets to the given folder as a single operation,
+// adding a numeric suffix to any snippet whose name is taken in the folder.
+func (m *Model) moveSnippets(snippets []Snippet, folder string) error {
+	if folder == "" || Folder(folder).virtual() {
+		return fmt.Errorf("invalid folder %q", folder)
+	}
+	var ops []operation
+	defer func() { m.pushBatch("move to folder", ops) }()
+	for _, s := range snippets {
+		to := s
+		to.Folder = folder
+		if to.Path() == s.Path() {
+			continue
+		}
+		op := &renameOp{from: s, to: m.uniqueSnippet(to)}
+		if err := op.redo(m); err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+	m.clearMarks()
+	return nil
+}
+
+// pushBatch records the operations performed by a bulk action, including
+// those performed before the action failed, so that they can be undone.
+func (m *Model) pushBatch(name string, ops []operation) {
+	if len(ops) > 0 {
+		m.history.push(&batchOp{name: name, ops: ops})
+	}
+	m.updateUsageViews()
+}
+
+// tagSnippets adds and removes tags from the snippets. Tags prefixed with a
+// minus sign are removed, the others are added.
+func (m *Model) tagSnippets(snippets []Snippet, tags string) error {
+	op := &metadataOp{name: "tag", before: snippets}
+	for _, s := range snippets {
+		s.Tags = slices.Clone(s.Tags)
+		for _, tag := range strings.Fields(tags) {
+			if strings.HasPrefix(tag, "-") {
+				s.Tags = slices.DeleteFunc(s.Tags, func(t string) bool { return t == tag[1:] })
+			} else if !slices.Contains(s.Tags, tag) {
+				s.Tags = append(s.Tags, tag)
+			}
+		}
+		op.after = append(op.after, s)
+	}
+	return m.do(op)
+}
+
+// favoriteSnippets marks the snippets as favorites, or unmarks them if they
+// all are favorites already.
+func (m *Model) favoriteSnippets(snippets []Snippet) error {
+	favorite := slices.ContainsFunc(snippets, func(s Snippet) bool { return !s.Favorite })
+	op := &metadataOp{name: "favorite", before: snippets}
+	for _, s := range snippets {
+		s.Favorite = favorite
+		op.after = append(op.after, s)
+	}
+	return m.do(op)
+}
+
+// copySnippets copies the concatenated contents of the snippets to the
+// clipboard.
+func (m *Model) copySnippets(snippets []Snippet) tea.Cmd {
+	m.recordUsage(snippets, usageCopy)
+	return func() tea.Msg {
+		var contents []string
+		for _, s := range snippets {
+			content, err := readSnippet(m.config, s)
+			if err != nil {
+				return changeStateMsg{navigatingState}
+			}
+			contents = append(contents, string(content))
+		}
+		clipboard.WriteAll(strings.Join(contents, "\n"))
+		return changeStateMsg{copyingState}
+	}
+}
+
+// exportSnippets writes the snippet files to dir, in a directory per folder.
+func exportSnippets(config Config, snippets []Snippet, dir string) error {
+	if strings.HasPrefix(dir, "~") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	for _, s := range snippets {
+		if s.Multi {
+			if _, err := materializeSnippet(config, s, filepath.Join(dir, s.Path()), true); err != nil {
+				return err
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(config.Home, s.Path()))
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, s.Path())
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prompt asks the user for a value to complete an action.
+type prompt struct {
+	input  textinput.Model
+	submit func(value string) tea.Cmd
+}
+
+// promptFor returns a Cmd asking the user for a value, which is passed to
+// submit once entered.
+func (m *Model) promptFor(title, placeholder string, submit func(value string) tea.Cmd) tea.Cmd {
+	input := newTextInput(placeholder)
+	input.Prompt = title + " "
+	m.prompt = &prompt{input: input, submit: submit}
+	return tea.Batch(m.prompt.input.Focus(), changeState(promptingState))
+}
+
+// bulkStatus returns a Cmd displaying the outcome of a bulk action.
+func (m *Model) bulkStatus(done string, n int, err error) tea.Cmd {
+	if err != nil {
+		return m.List().NewStatusMessage(fmt.Sprintf("Unable to update snippets: %v", err))
+	}
+	noun := "snippets"
+	if n == 1 {
+		noun = "snippet"
+	}
+	return tea.Batch(m.List().NewStatusMessage(fmt.Sprintf("%s %d %s", done, n, noun)), m.refresh())
+}
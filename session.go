@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Session is the state of the interface restored between runs.
+type Session struct {
+	CurrentFolder string
+	// CurrentSnippet is the ID of the selected snippet, or its file name for
+	// sessions saved before snippets had an ID.
+	CurrentSnippet string
+	Pane           pane   `json:",omitempty"`
+	Filter         string `json:",omitempty"`
+	Scroll         int    `json:",omitempty"`
+	ShowHelp       bool   `json:",omitempty"`
+	Sort           string `json:",omitempty"`
+	// Selected holds the ID of the selected snippet of each folder.
+	Selected map[Folder]string `json:",omitempty"`
+}
+
+// session returns the named session, or the default session if name is empty.
+func (s State) session(name string) Session {
+	if name == "" {
+		return s.Session
+	}
+	return s.Sessions[name]
+}
+
+// setSession replaces the named session, or the default session if name is
+// empty.
+func (s *State) setSession(name string, session Session) {
+	if name == "" {
+		s.Session = session
+		return
+	}
+	if s.Sessions == nil {
+		s.Sessions = map[string]Session{}
+	}
+	s.Sessions[name] = session
+}
+
+// snippetKey returns the key identifying the snippet in a session.
+func snippetKey(s Snippet) string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return s.File
+}
+
+// selectKey selects the snippet of the list identified by key, either its ID
+// or its file name.
+func selectKey(li *list.Model, key string) {
+	if key == "" {
+		return
+	}
+	for idx, item := range li.Items() {
+		if s, ok := item.(Snippet); ok && (s.ID == key || s.File == key) {
+			li.Select(idx)
+			return
+		}
+	}
+}
+
+// currentSession returns the state of the interface to restore on the next
+// run.
+func (m *Model) currentSession() Session {
+	s := Session{
+		CurrentFolder:  string(m.selectedFolder()),
+		CurrentSnippet: snippetKey(m.selectedSnippet()),
+		Pane:           m.pane,
+		Scroll:         m.Code.YOffset,
+		ShowHelp:       m.help.ShowAll,
+		Sort:           m.sort.String(),
+		Selected:       map[Folder]string{},
+	}
+	if m.List().FilterState() != list.Unfiltered {
+		s.Filter = m.List().FilterValue()
+	}
+	for folder, li := range m.Lists {
+		if item := li.SelectedItem(); item != nil && !folder.virtual() {
+			s.Selected[folder] = snippetKey(item.(Snippet))
+		}
+	}
+	return s
+}
+
+// restoreSession restores the state of the interface from a previous run.
+func (m *Model) restoreSession(s Session) {
+	if mode, err := parseSortMode(s.Sort); err == nil {
+		m.sort = mode
+		m.sortLists()
+	}
+	for folder, key := range s.Selected {
+		if li, ok := m.Lists[folder]; ok {
+			selectKey(li, key)
+		}
+	}
+	for idx, item := range m.Folders.Items() {
+		if string(item.(Folder)) == s.CurrentFolder {
+			m.Folders.Select(idx)
+			break
+		}
+	}
+	selectKey(m.List(), s.CurrentSnippet)
+	if s.Pane >= 0 && s.Pane < maxPane {
+		m.pane = s.Pane
+	}
+	m.help.ShowAll = s.ShowHelp
+	m.scroll = s.Scroll
+	if s.Filter != "" {
+		applyFilter(m.List(), s.Filter)
+	}
+}
+
+// applyFilter filters the list with the query as if the user had typed it.
+func applyFilter(li *list.Model, query string) {
+	li.FilterInput.SetValue(query)
+	*li, _ = li.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(li.KeyMap.Filter.Keys()[0])})
+	if cmd := li.SetItems(li.Items()); cmd != nil {
+		*li, _ = li.Update(cmd())
+	}
+	*li, _ = li.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
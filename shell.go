@@ -0,0 +1,49 @@
+package main
+
+import "fmt"
+
+// shellInits are the scripts binding ctrl+x ctrl+n to insert a snippet picked
+// with `nap pick` into the command line, by shell.
+var shellInits = map[string]string{
+	"bash": `__nap_pick() {
+  local snippet
+  snippet="$(nap pick)"
+  [ -n "$snippet" ] || return
+  READLINE_LINE="${READLINE_LINE:0:$READLINE_POINT}${snippet}${READLINE_LINE:$READLINE_POINT}"
+  READLINE_POINT=$((READLINE_POINT + ${#snippet}))
+}
+bind -x '"\C-x\C-n": __nap_pick'
+`,
+	"zsh": `__nap_pick() {
+  local snippet
+  snippet="$(nap pick < /dev/tty)"
+  if [[ -n "$snippet" ]]; then
+    LBUFFER+="$snippet"
+  fi
+  zle reset-prompt
+}
+zle -N __nap_pick
+bindkey '^X^N' __nap_pick
+`,
+	"fish": `function __nap_pick
+    set -l snippet (nap pick | string collect)
+    if test -n "$snippet"
+        commandline --insert -- $snippet
+    end
+    commandline --function repaint
+end
+bind \cx\cn __nap_pick
+`,
+}
+
+// runShellInit runs the `nap shell-init` subcommand, printing the script to
+// source in the given shell.
+func runShellInit(args []string) {
+	if len(args) == 1 {
+		if script, ok := shellInits[args[0]]; ok {
+			fmt.Print(script)
+			return
+		}
+	}
+	fmt.Println("usage: nap shell-init bash|zsh|fish")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// parseInterspersed parses the flags wherever they appear among the arguments
+// and returns the other arguments. The arguments after -- are all returned as
+// is.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		// Parse stops after the -- it consumes
+		if parsed := len(args) - flags.NArg(); parsed > 0 && args[parsed-1] == "--" {
+			return append(rest, flags.Args()...), nil
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// parseLineRange parses a range of lines of the form 10-25, 10- (to the last
+// line) or 10. The end of the range is 0 when it is the last line.
+func parseLineRange(s string) (int, int, error) {
+	start, end, found := strings.Cut(s, "-")
+	from, err := strconv.Atoi(start)
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("invalid line range %q", s)
+	}
+	if !found {
+		return from, from, nil
+	}
+	if end == "" {
+		return from, 0, nil
+	}
+	to, err := strconv.Atoi(end)
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid line range %q", s)
+	}
+	return from, to, nil
+}
+
+// lineRange returns the lines from and to of the content, numbered from 1,
+// with their line endings. A to of 0 or past the last line ends the range at
+// the last line.
+func lineRange(content string, from, to int) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if to == 0 || to > len(lines) {
+		to = len(lines)
+	}
+	if from < 1 || from > to {
+		return "", fmt.Errorf("line %d is past the end of the snippet (%d lines)", from, len(lines))
+	}
+	return strings.Join(lines[from-1:to], ""), nil
+}
+
+// runShow runs the `nap show` subcommand, printing the snippet or a range of
+// its lines to stdout, and returns the exit code.
+func runShow(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	exact := flags.Bool("exact", false, "only show the snippet at the exact folder/name.ext")
+	raw := flags.Bool("raw", false, "print the snippet as is, without highlighting or rendering it")
+	lines := flags.String("lines", "", "only print the range of lines, like 10-25")
+	file := flags.String("file", "", "only print the file of a multi-file snippet")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 1 {
+		fmt.Println("usage: nap show [--exact] [--raw] [--lines 10-25] [--file <file>] <snippet>")
+		return 2
+	}
+	snippet, ok := lookupSnippet(args[0], snippets, *exact)
+	if !ok {
+		return 1
+	}
+
+	content := snippet.Content(config, false)
+	language := snippet.syntax()
+	if *file != "" {
+		if !snippet.Multi {
+			fmt.Fprintf(os.Stderr, "%s is not a multi-file snippet\n", snippet)
+			return 1
+		}
+		b, err := readFragment(config, snippet, *file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		content, language = string(b), fragmentLanguage(*file)
+	}
+	if *lines != "" {
+		from, to, err := parseLineRange(*lines)
+		if err == nil {
+			content, err = lineRange(content, from, to)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if isatty.IsTerminal(os.Stdout.Fd()) && !*raw && !snippet.Attachment {
+		if snippet.Multi && *file == "" && *lines == "" {
+			// each file is highlighted in its own language
+			content = snippet.Content(config, true)
+		} else {
+			content = highlightContent(config, content, language)
+		}
+	}
+	fmt.Print(content)
+	recordUsage(snippet, usagePrint)
+	return 0
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -32,6 +34,8 @@ var defaultSnippet = Snippet{
 // Snippet represents a snippet of code in a language.
 // It is nested within a folder and can be tagged with metadata.
 type Snippet struct {
+	// ID identifies the snippet across renames.
+	ID       string    `json:"id,omitempty"`
 	Date     time.Time `json:"date"`
 	Folder   string    `json:"folder"`
 	Name     string    `json:"title"`
@@ -39,11 +43,54 @@ type Snippet struct {
 	Language string    `json:"language"`
 	Tags     []string  `json:"tags"`
 	Favorite bool      `json:"favorite"`
+	// Description says what the snippet is for.
+	Description string `json:"description,omitempty"`
+	// Source is the file or URL the snippet was imported from.
+	Source string `json:"source,omitempty"`
+	// Hash is the hash of the content nap last saved to the snippet, which
+	// finds duplicates without reading every snippet.
+	Hash string `json:"hash,omitempty"`
+	// Attachment is set when the snippet holds binary content, which is
+	// stored as is and never highlighted.
+	Attachment bool `json:"attachment,omitempty"`
+	// Multi is set when the snippet is a directory of several files rather
+	// than a single file.
+	Multi bool `json:"multi,omitempty"`
+	// Order is the position of the snippet in its folder when sorted
+	// manually.
+	Order int `json:"order"`
 }
 
-// String returns the folder/name.ext of the snippet.
+// newSnippetID returns a new random snippet ID.
+func newSnippetID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// String returns the folder/name.ext of the snippet, or the folder/name of a
+// multi-file snippet or of a file without extension.
 func (s Snippet) String() string {
-	return fmt.Sprintf("%s/%s.%s", s.Folder, s.Name, s.Language)
+	return fmt.Sprintf("%s/%s", s.Folder, s.fileName())
+}
+
+// fileName returns the name of the file or directory of the snippet. Files
+// without extension, like Dockerfile, have no language.
+func (s Snippet) fileName() string {
+	if s.Multi || s.Language == "" {
+		return s.Name
+	}
+	return fmt.Sprintf("%s.%s", s.Name, s.Language)
+}
+
+// syntax returns the language the snippet is highlighted in: its language,
+// or the name of a file without extension, which recognizes files like
+// Dockerfile.
+func (s Snippet) syntax() string {
+	if s.Language == "" {
+		return fragmentLanguage(s.Name)
+	}
+	return s.Language
 }
 
 // LegacyPath returns the legacy path <folder>-<file>
@@ -56,23 +103,39 @@ func (s Snippet) Path() string {
 	return filepath.Join(s.Folder, s.File)
 }
 
-// Content returns the snippet contents.
-func (s Snippet) Content(highlight bool) string {
-	config := readConfig()
+// Content returns the snippet contents, highlighted for the terminal or
+// rendered if it is Markdown when highlight is set. Attachments are returned
+// as is.
+func (s Snippet) Content(config Config, highlight bool) string {
+	if s.Multi {
+		content, _ := joinFragments(config, s, highlight && !s.Attachment)
+		return content
+	}
 	file := filepath.Join(config.Home, s.Path())
 	content, err := os.ReadFile(file)
 	if err != nil {
 		return ""
 	}
 
-	if !highlight {
+	if !highlight || s.Attachment {
 		return string(content)
 	}
+	return highlightContent(config, string(content), s.syntax())
+}
+
+// highlightContent returns the content highlighted for the terminal, or
+// rendered if it is Markdown.
+func highlightContent(config Config, content, language string) string {
+	if isMarkdown(language) {
+		if rendered, err := renderMarkdown(config, content, markdownWidth); err == nil {
+			return rendered
+		}
+	}
 
 	var b bytes.Buffer
-	err = quick.Highlight(&b, string(content), s.Language, "terminal16m", config.Theme)
+	err := quick.Highlight(&b, content, language, "terminal16m", config.Theme)
 	if err != nil {
-		return string(content)
+		return content
 	}
 	return b.String()
 }
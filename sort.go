@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"golang.org/x/exp/slices"
+)
+
+// sortMode is the order in which snippets are listed.
+type sortMode int
+
+const (
+	sortManual sortMode = iota
+	sortName
+	sortDate
+	sortLanguage
+	sortRecent
+)
+
+var sortModes = []string{"manual", "name", "date", "language", "recent"}
+
+// String returns the name of the sort mode.
+func (s sortMode) String() string {
+	return sortModes[s]
+}
+
+// next returns the sort mode following this one.
+func (s sortMode) next() sortMode {
+	return (s + 1) % sortMode(len(sortModes))
+}
+
+// parseSortMode returns the sort mode with the given name.
+func parseSortMode(name string) (sortMode, error) {
+	i := slices.Index(sortModes, strings.ToLower(name))
+	if i < 0 {
+		return sortManual, fmt.Errorf("unknown sort mode %q, must be one of %s", name, strings.Join(sortModes, ", "))
+	}
+	return sortMode(i), nil
+}
+
+// sortSnippets sorts the snippets in place. The sort is stable, so snippets
+// that compare equal keep their relative order.
+func sortSnippets(usage Usage, snippets []Snippet, mode sortMode) {
+	slices.SortStableFunc(snippets, func(a, b Snippet) int {
+		switch mode {
+		case sortName:
+			return compareNames(a, b)
+		case sortDate:
+			return compareTimes(b.Date, a.Date)
+		case sortLanguage:
+			if c := strings.Compare(a.Language, b.Language); c != 0 {
+				return c
+			}
+			return compareNames(a, b)
+		case sortRecent:
+			return compareTimes(usage.last(b.Path()), usage.last(a.Path()))
+		}
+		return a.Order - b.Order
+	})
+}
+
+// compareNames compares the snippets by name, ignoring case.
+func compareNames(a, b Snippet) int {
+	if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Language, b.Language)
+}
+
+// compareTimes compares the times chronologically.
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// folderOrderBounds returns the lowest and highest order of the snippets in
+// the folder.
+func folderOrderBounds(snippets []Snippet, folder string) (int, int) {
+	var lo, hi int
+	var found bool
+	for _, s := range snippets {
+		if s.Folder != folder {
+			continue
+		}
+		if !found || s.Order < lo {
+			lo = s.Order
+		}
+		if !found || s.Order > hi {
+			hi = s.Order
+		}
+		found = true
+	}
+	return lo, hi
+}
+
+// listSnippetsOf returns the snippets of the list.
+func listSnippetsOf(li *list.Model) []Snippet {
+	snippets := make([]Snippet, 0, len(li.Items()))
+	for _, item := range li.Items() {
+		if s, ok := item.(Snippet); ok {
+			snippets = append(snippets, s)
+		}
+	}
+	return snippets
+}
+
+// renumber sets the manual order of the snippets of the list to their
+// position in it.
+func renumber(li *list.Model) {
+	for i, s := range listSnippetsOf(li) {
+		if s.Order != i {
+			s.Order = i
+			li.SetItem(i, s)
+		}
+	}
+}
+
+// sortLists sorts every snippet list with the current sort mode, keeping the
+// selected snippet of each list selected.
+func (m *Model) sortLists() {
+	for folder, li := range m.Lists {
+		if folder.virtual() {
+			// the Recent and Frequent folders have their own order
+			continue
+		}
+		var selected string
+		if item := li.SelectedItem(); item != nil {
+			selected = item.(Snippet).Path()
+		}
+		snippets := listSnippetsOf(li)
+		sortSnippets(m.usage, snippets, m.sort)
+		items := make([]list.Item, len(snippets))
+		for i, s := range snippets {
+			items[i] = s
+			if s.Path() == selected {
+				li.Select(i)
+			}
+		}
+		li.SetItems(items)
+	}
+}
+
+// cycleSort switches to the next sort mode.
+func (m *Model) cycleSort() {
+	m.sort = m.sort.next()
+	m.sortLists()
+}
+
+// allSnippets returns the snippets of every folder, in the folders' order and
+// in each folder's manual order.
+func (m *Model) allSnippets() []Snippet {
+	var snippets []Snippet
+	for _, folder := range m.snippetFolders() {
+		folderSnippets := listSnippetsOf(m.Lists[folder])
+		sortSnippets(nil, folderSnippets, sortManual)
+		snippets = append(snippets, folderSnippets...)
+	}
+	return snippets
+}
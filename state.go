@@ -11,8 +11,12 @@ import (
 
 // State is application state between runs
 type State struct {
-	CurrentFolder  string
-	CurrentSnippet string
+	// Session is the default session, used unless a named session is
+	// requested.
+	Session
+	Sessions map[string]Session `json:",omitempty"`
+	// Usage records when each snippet was copied, printed or edited.
+	Usage Usage `json:",omitempty"`
 }
 
 // Save saves the state of the application
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// defaultMaxStdinSize is the largest content saved from stdin by default.
+const defaultMaxStdinSize = 10 << 20
+
+// binarySniffSize is how many bytes of the content are looked at to tell
+// whether it is binary, like git does.
+const binarySniffSize = 8000
+
+// attachmentLanguage is the language of attachments whose name has no
+// extension.
+const attachmentLanguage = "bin"
+
+// errTooLarge is returned when stdin, or a fetched URL, holds more than the
+// size limit.
+var errTooLarge = errors.New("larger than max_stdin_size")
+
+// readStdin returns the bytes piped in to the command line interface as is,
+// reading at most limit bytes when limit is positive. Redirected files are
+// read at once after checking their size; pipes are streamed.
+func readStdin(limit int64) (string, error) {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return "", nil
+	}
+
+	if stat.Mode()&os.ModeCharDevice != 0 {
+		return "", nil
+	}
+
+	var size int64
+	if stat.Mode().IsRegular() {
+		size = stat.Size()
+	}
+	content, err := readLimited(os.Stdin, size, limit)
+	if errors.Is(err, errTooLarge) {
+		return "", fmt.Errorf("stdin is %w", err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read stdin: %w", err)
+	}
+	return content, nil
+}
+
+// readLimited reads r until EOF, failing with errTooLarge past limit bytes
+// when limit is positive. A known size avoids growing the buffer.
+func readLimited(r io.Reader, size, limit int64) (string, error) {
+	if limit > 0 {
+		if size > limit {
+			return "", fmt.Errorf("%w (%d bytes)", errTooLarge, limit)
+		}
+		r = io.LimitReader(r, limit+1)
+	}
+
+	var b bytes.Buffer
+	if size > 0 {
+		b.Grow(int(size) + bytes.MinRead)
+	}
+	if _, err := b.ReadFrom(r); err != nil {
+		return "", err
+	}
+	if limit > 0 && int64(b.Len()) > limit {
+		return "", fmt.Errorf("%w (%d bytes)", errTooLarge, limit)
+	}
+	return b.String(), nil
+}
+
+// isBinary reports whether the content looks binary, that is whether its
+// first bytes hold a NUL byte. Text that is not valid UTF-8 is not binary.
+func isBinary(content string) bool {
+	if len(content) > binarySniffSize {
+		content = content[:binarySniffSize]
+	}
+	return strings.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadLimited(t *testing.T) {
+	content := "caf\xe9\x00\r\n"
+	got, err := readLimited(strings.NewReader(content), 0, 0)
+	if err != nil || got != content {
+		t.Logf("bytes should be read as is: got %q, %v", got, err)
+		t.FailNow()
+	}
+	got, err = readLimited(strings.NewReader(content), int64(len(content)), int64(len(content)))
+	if err != nil || got != content {
+		t.Logf("content of the limit size should be read: got %q, %v", got, err)
+		t.FailNow()
+	}
+	if _, err := readLimited(strings.NewReader(content), 0, 4); !errors.Is(err, errTooLarge) {
+		t.Logf("streamed content over the limit should fail: got %v", err)
+		t.FailNow()
+	}
+	if _, err := readLimited(strings.NewReader(content), int64(len(content)), 4); !errors.Is(err, errTooLarge) {
+		t.Logf("content of a known size over the limit should fail: got %v", err)
+		t.FailNow()
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		content string
+		binary  bool
+	}{
+		{"package main\n", false},
+		{"caf\xe9\n", false},
+		{"\x89PNG\r\n\x1a\n\x00\x00", true},
+		{strings.Repeat("a", binarySniffSize) + "\x00", false},
+	}
+	for _, test := range tests {
+		if got := isBinary(test.content); got != test.binary {
+			t.Logf("isBinary(%.20q) = %v, want %v", test.content, got, test.binary)
+			t.FailNow()
+		}
+	}
+}
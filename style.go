@@ -41,6 +41,7 @@ type SnippetsBaseStyle struct {
 	DeletedTitleBar    lipgloss.Style
 	DeletedTitle       lipgloss.Style
 	DeletedSubtitle    lipgloss.Style
+	Marked             lipgloss.Style
 }
 
 // FoldersBaseStyle holds the neccessary styling for the folders pane of
@@ -62,6 +63,11 @@ type ContentBaseStyle struct {
 	LineNumber   lipgloss.Style
 	EmptyHint    lipgloss.Style
 	EmptyHintKey lipgloss.Style
+	Tab          lipgloss.Style
+	DiffHeader   lipgloss.Style
+	DiffHunk     lipgloss.Style
+	Deleted      lipgloss.Style
+	Inserted     lipgloss.Style
 }
 
 // Styles is the struct of all styles for the application.
@@ -102,6 +108,7 @@ func DefaultStyles(config Config) Styles {
 				DeletedTitleBar:    lipgloss.NewStyle().Background(red).Width(35-2).Margin(0, 1, 1, 1).Padding(0, 1).Foreground(textInvert),
 				DeletedTitle:       lipgloss.NewStyle().Foreground(brightRed),
 				DeletedSubtitle:    lipgloss.NewStyle().Foreground(red),
+				Marked:             lipgloss.NewStyle().Foreground(green),
 			},
 			Blurred: SnippetsBaseStyle{
 				Base:               lipgloss.NewStyle().Width(35),
@@ -116,6 +123,7 @@ func DefaultStyles(config Config) Styles {
 				DeletedTitleBar:    lipgloss.NewStyle().Background(red).Width(35-2).Margin(0, 1, 1, 1).Padding(0, 1),
 				DeletedTitle:       lipgloss.NewStyle().Foreground(brightRed),
 				DeletedSubtitle:    lipgloss.NewStyle().Foreground(red),
+				Marked:             lipgloss.NewStyle().Foreground(green),
 			},
 		},
 		Folders: FoldersStyle{
@@ -142,6 +150,11 @@ func DefaultStyles(config Config) Styles {
 				LineNumber:   lipgloss.NewStyle().Foreground(text),
 				EmptyHint:    lipgloss.NewStyle().Foreground(text),
 				EmptyHintKey: lipgloss.NewStyle().Foreground(primary),
+				Tab:          lipgloss.NewStyle().Foreground(subtext).Margin(0, 0, 1, 1).Padding(0, 1),
+				DiffHeader:   lipgloss.NewStyle().Bold(true),
+				DiffHunk:     lipgloss.NewStyle().Foreground(primary),
+				Deleted:      lipgloss.NewStyle().Foreground(red),
+				Inserted:     lipgloss.NewStyle().Foreground(green),
 			},
 			Blurred: ContentBaseStyle{
 				Base:         lipgloss.NewStyle().Margin(0, 1),
@@ -150,6 +163,11 @@ func DefaultStyles(config Config) Styles {
 				LineNumber:   lipgloss.NewStyle().Foreground(subtext),
 				EmptyHint:    lipgloss.NewStyle().Foreground(text),
 				EmptyHintKey: lipgloss.NewStyle().Foreground(primary),
+				Tab:          lipgloss.NewStyle().Foreground(subtext).Margin(0, 0, 1, 1).Padding(0, 1),
+				DiffHeader:   lipgloss.NewStyle().Bold(true),
+				DiffHunk:     lipgloss.NewStyle().Foreground(primary),
+				Deleted:      lipgloss.NewStyle().Foreground(red),
+				Inserted:     lipgloss.NewStyle().Foreground(green),
 			},
 		},
 	}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// trashFolder is the folder under the nap home where deleted snippets are kept
+// until they are restored or purged.
+const trashFolder = ".trash"
+
+// trashFile is the file within the trash folder holding the metadata of the
+// deleted snippets.
+const trashFile = "trash.json"
+
+// TrashedSnippet is a deleted snippet kept in the trash.
+type TrashedSnippet struct {
+	Snippet   Snippet   `json:"snippet"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// File is the name of the snippet file within the trash folder.
+	File string `json:"file"`
+}
+
+// String returns the folder/name.ext of the deleted snippet.
+func (t TrashedSnippet) String() string {
+	return t.Snippet.String()
+}
+
+// Path returns the path <trash>/<file> of the deleted snippet file.
+func (t TrashedSnippet) Path() string {
+	return filepath.Join(trashFolder, t.File)
+}
+
+// item returns the snippet displayed for the deleted snippet in the Trash
+// folder.
+func (t TrashedSnippet) item() Snippet {
+	s := t.Snippet
+	s.Folder = trashFolder
+	s.File = t.File
+	s.Date = t.DeletedAt
+	return s
+}
+
+// readTrash returns the deleted snippets kept in the trash.
+func readTrash(config Config) []TrashedSnippet {
+	var trash []TrashedSnippet
+	file := filepath.Join(config.Home, trashFolder, trashFile)
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("Unable to read %s file, %+v\n", file, err)
+		}
+		return trash
+	}
+	if err := json.Unmarshal(b, &trash); err != nil {
+		fmt.Printf("Unable to unmarshal %s file, %+v\n", file, err)
+	}
+	return trash
+}
+
+// writeTrash saves the metadata of the deleted snippets kept in the trash.
+func writeTrash(config Config, trash []TrashedSnippet) error {
+	b, err := json.Marshal(trash)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(config.Home, trashFolder)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, trashFile), b, 0o644)
+}
+
+// trashSnippet moves the snippet file to the trash.
+func trashSnippet(config Config, s Snippet) (TrashedSnippet, error) {
+	t := TrashedSnippet{
+		Snippet:   s,
+		DeletedAt: time.Now(),
+		File:      fmt.Sprintf("%d-%s", time.Now().UnixNano(), s.File),
+	}
+	if err := os.MkdirAll(filepath.Join(config.Home, trashFolder), os.ModePerm); err != nil {
+		return t, err
+	}
+	if err := os.Rename(filepath.Join(config.Home, s.Path()), filepath.Join(config.Home, t.Path())); err != nil {
+		return t, err
+	}
+	return t, writeTrash(config, append(readTrash(config), t))
+}
+
+// restoreTrashed moves the deleted snippet back to its folder. If its path is
+// taken in the meantime, the snippet is restored with a numeric suffix.
+func restoreTrashed(config Config, t TrashedSnippet, taken func(path string) bool) (Snippet, error) {
+	s := uniqueSnippet(t.Snippet, taken)
+	dir := filepath.Join(config.Home, s.Folder)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return s, err
+	}
+	if err := os.Rename(filepath.Join(config.Home, t.Path()), filepath.Join(config.Home, s.Path())); err != nil {
+		return s, err
+	}
+	return s, removeTrashed(config, t)
+}
+
+// purgeTrashed permanently deletes the snippet from the trash.
+func purgeTrashed(config Config, t TrashedSnippet) error {
+	// multi-file snippets are directories
+	err := os.RemoveAll(filepath.Join(config.Home, t.Path()))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return removeTrashed(config, t)
+}
+
+// removeTrashed removes the deleted snippet from the trash metadata.
+func removeTrashed(config Config, t TrashedSnippet) error {
+	trash := readTrash(config)
+	var idx int
+	for _, other := range trash {
+		if other.File != t.File {
+			trash[idx] = other
+			idx++
+		}
+	}
+	return writeTrash(config, trash[:idx])
+}
+
+// findTrashed returns the deleted snippet stored in the given trash file.
+func findTrashed(config Config, file string) (TrashedSnippet, bool) {
+	for _, t := range readTrash(config) {
+		if t.File == file {
+			return t, true
+		}
+	}
+	return TrashedSnippet{}, false
+}
+
+// purgeExpiredTrash permanently deletes the snippets that have been in the
+// trash for longer than the configured retention period.
+func purgeExpiredTrash(config Config) {
+	if config.TrashRetention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-time.Duration(config.TrashRetention) * Day)
+	for _, t := range readTrash(config) {
+		if t.DeletedAt.Before(cutoff) {
+			if err := purgeTrashed(config, t); err != nil {
+				fmt.Printf("could not purge %s from the trash: %v\n", t, err)
+			}
+		}
+	}
+}
+
+// runTrash runs the `nap trash` subcommands.
+func runTrash(args []string, config Config, snippets []Snippet) {
+	cmd := "list"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	trash := readTrash(config)
+	switch cmd {
+	case "list":
+		for _, t := range trash {
+			fmt.Printf("%s (deleted %s)\n", t, humanizeTime(t.DeletedAt))
+		}
+	case "restore":
+		if len(args) < 2 {
+			fmt.Println("usage: nap trash restore <snippet>")
+			return
+		}
+		var restored []Snippet
+		for _, search := range args[1:] {
+			t, ok := findTrashedByName(search, trash)
+			if !ok {
+				fmt.Printf("could not find %q in the trash\n", search)
+				continue
+			}
+			s, err := restoreTrashed(config, t, func(path string) bool {
+				return snippetPathTaken(config, append(snippets, restored...), path)
+			})
+			if err != nil {
+				fmt.Printf("could not restore %s: %v\n", t, err)
+				continue
+			}
+			restored = append(restored, s)
+			fmt.Printf("restored %s\n", s)
+		}
+		if len(restored) > 0 {
+			writeSnippets(config, append(snippets, restored...))
+		}
+	case "empty":
+		for _, t := range trash {
+			if err := purgeTrashed(config, t); err != nil {
+				fmt.Printf("could not purge %s: %v\n", t, err)
+			}
+		}
+	default:
+		fmt.Println("usage: nap trash [list|restore <snippet>|empty]")
+	}
+}
+
+// findTrashedByName returns the most recently deleted snippet in the trash
+// whose folder/name.ext matches the search, falling back to the snippet name.
+func findTrashedByName(search string, trash []TrashedSnippet) (TrashedSnippet, bool) {
+	for i := len(trash) - 1; i >= 0; i-- {
+		if trash[i].String() == search {
+			return trash[i], true
+		}
+	}
+	for i := len(trash) - 1; i >= 0; i-- {
+		if trash[i].Snippet.Name == search {
+			return trash[i], true
+		}
+	}
+	return TrashedSnippet{}, false
+}
+
+// snippetPathTaken reports whether a snippet or file already exists at the
+// given snippet path.
+func snippetPathTaken(config Config, snippets []Snippet, path string) bool {
+	for _, s := range snippets {
+		if s.Path() == path {
+			return true
+		}
+	}
+	_, err := os.Stat(filepath.Join(config.Home, path))
+	return err == nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/slices"
+)
+
+// the virtual folders listing the snippets by usage.
+const (
+	recentFolder   = ".recent"
+	frequentFolder = ".frequent"
+)
+
+// usageViewSize is the number of snippets listed in the Recent and Frequent
+// folders.
+const usageViewSize = 20
+
+// maxUsageEvents is the number of events kept per snippet.
+const maxUsageEvents = 100
+
+// the actions recorded as snippet usage.
+const (
+	usageCopy  = "copy"
+	usagePrint = "print"
+	usageEdit  = "edit"
+	usageRun   = "run"
+)
+
+// UsageEvent is a single use of a snippet.
+type UsageEvent struct {
+	Action string    `json:"action"`
+	Time   time.Time `json:"time"`
+}
+
+// Usage holds the usage events of the snippets, keyed by their path.
+type Usage map[string][]UsageEvent
+
+// record adds an event for the snippet at path, dropping the oldest events
+// past maxUsageEvents.
+func (u Usage) record(path, action string) {
+	events := append(u[path], UsageEvent{Action: action, Time: time.Now()})
+	if len(events) > maxUsageEvents {
+		events = events[len(events)-maxUsageEvents:]
+	}
+	u[path] = events
+}
+
+// rename moves the events of the snippet at from to the snippet at to.
+func (u Usage) rename(from, to string) {
+	if events, ok := u[from]; ok && from != to {
+		u.add(to, events)
+		delete(u, from)
+	}
+}
+
+// add merges the events into those of the snippet at path in time order,
+// leaving out the events it has already and dropping the oldest events past
+// maxUsageEvents.
+func (u Usage) add(path string, events []UsageEvent) {
+	merged := slices.Clone(u[path])
+	for _, e := range events {
+		if !slices.ContainsFunc(merged, func(m UsageEvent) bool { return m.Action == e.Action && m.Time.Equal(e.Time) }) {
+			merged = append(merged, e)
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b UsageEvent) int { return compareTimes(a.Time, b.Time) })
+	if len(merged) > maxUsageEvents {
+		merged = merged[len(merged)-maxUsageEvents:]
+	}
+	u[path] = merged
+}
+
+// merge adds the events of other recorded after since, which other nap
+// commands recorded while the usage was in use.
+func (u Usage) merge(other Usage, since time.Time) {
+	for path, events := range other {
+		events = slices.DeleteFunc(slices.Clone(events), func(e UsageEvent) bool { return !e.Time.After(since) })
+		if len(events) > 0 {
+			u.add(path, events)
+		}
+	}
+}
+
+// count returns the number of times the snippet at path was used.
+func (u Usage) count(path string) int {
+	return len(u[path])
+}
+
+// last returns the last time the snippet at path was used.
+func (u Usage) last(path string) time.Time {
+	events := u[path]
+	if len(events) == 0 {
+		return time.Time{}
+	}
+	return events[len(events)-1].Time
+}
+
+// frecency scores the snippet at path by how often and how recently it was
+// used, each use weighing less as it gets older.
+func (u Usage) frecency(path string) int {
+	now := time.Now()
+	var score int
+	for _, e := range u[path] {
+		switch age := now.Sub(e.Time); {
+		case age < 4*Day:
+			score += 100
+		case age < 2*Week:
+			score += 70
+		case age < Month:
+			score += 50
+		case age < 3*Month:
+			score += 30
+		default:
+			score += 10
+		}
+	}
+	return score
+}
+
+// maxUsageBoost is the most the frecency of a snippet adds to the score of
+// its fuzzy match, a point per recent use, enough to put a well used snippet
+// before those matching about as well but not before a clearly better match.
+const maxUsageBoost = 10
+
+// rank returns the score of the fuzzy match of the snippets, boosted by the
+// frecency of the matched snippet so that well used snippets come first.
+func rank(match fuzzy.Match, snippets []Snippet, usage Usage) int {
+	boost := usage.frecency(snippets[match.Index].Path()) / 100
+	if boost > maxUsageBoost {
+		boost = maxUsageBoost
+	}
+	return match.Score + boost
+}
+
+// recordUsage saves a use of the snippet to the application state.
+func recordUsage(s Snippet, action string) {
+	state := readState()
+	if state.Usage == nil {
+		state.Usage = Usage{}
+	}
+	state.Usage.record(s.Path(), action)
+	if err := state.Save(); err != nil {
+		fmt.Printf("Unable to save usage of %s: %v\n", s, err)
+	}
+}
+
+// usageView returns the most used snippets, ordered by last use for the Recent
+// folder or by use count for the Frequent folder.
+func usageView(usage Usage, snippets []Snippet, folder Folder) []Snippet {
+	snippets = slices.DeleteFunc(slices.Clone(snippets), func(s Snippet) bool {
+		return usage.count(s.Path()) == 0
+	})
+	slices.SortStableFunc(snippets, func(a, b Snippet) int {
+		if folder == frequentFolder {
+			if c := usage.count(b.Path()) - usage.count(a.Path()); c != 0 {
+				return c
+			}
+		}
+		return compareTimes(usage.last(b.Path()), usage.last(a.Path()))
+	})
+	if len(snippets) > usageViewSize {
+		snippets = snippets[:usageViewSize]
+	}
+	return snippets
+}
+
+// recordUsage records a use of the snippets and updates the usage folders.
+func (m *Model) recordUsage(snippets []Snippet, action string) {
+	for _, s := range snippets {
+		m.usage.record(s.Path(), action)
+	}
+	m.updateUsageViews()
+}
+
+// updateUsageViews rebuilds the Recent and Frequent folders, keeping their
+// selected snippet selected.
+func (m *Model) updateUsageViews() {
+	snippets := m.allSnippets()
+	for _, folder := range []Folder{recentFolder, frequentFolder} {
+		li, ok := m.Lists[folder]
+		if !ok {
+			continue
+		}
+		var selected string
+		if item := li.SelectedItem(); item != nil {
+			selected = item.(Snippet).Path()
+		}
+		view := usageView(m.usage, snippets, folder)
+		items := make([]list.Item, len(view))
+		for i, s := range view {
+			items[i] = s
+		}
+		li.SetItems(items)
+		if i := slices.IndexFunc(view, func(s Snippet) bool { return s.Path() == selected }); i >= 0 {
+			li.Select(i)
+		} else if len(items) > 0 && li.Index() >= len(items) {
+			li.Select(len(items) - 1)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"golang.org/x/exp/slices"
+)
+
+// placeholderName matches the names of placeholders.
+var placeholderName = regexp.MustCompile(`^\w+$`)
+
+// placeholderValues are the values of placeholders given with --set.
+type placeholderValues map[string]string
+
+func (v placeholderValues) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+// Set parses a name=value placeholder value.
+func (v placeholderValues) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || !placeholderName.MatchString(name) {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// templateFiles returns the files of the snippets of the folder, with the
+// paths they have in the folder.
+func templateFiles(config Config, snippets []Snippet, folder string) ([]snippetFile, []Snippet, error) {
+	var files []snippetFile
+	var used []Snippet
+	for _, s := range snippets {
+		if s.Folder != folder {
+			continue
+		}
+		sFiles, err := snippetFiles(config, s)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range sFiles {
+			if s.Multi {
+				f.name = s.File + "/" + f.name
+			}
+			files = append(files, f)
+		}
+		used = append(used, s)
+	}
+	if len(used) == 0 {
+		return nil, nil, fmt.Errorf("no snippets in folder %s", folder)
+	}
+	return files, used, nil
+}
+
+// filePlaceholders returns the names of the placeholders of the paths and
+// contents of the files, in the order they first appear. The content of
+// binary files is left out.
+func filePlaceholders(files []snippetFile) []string {
+	var names []string
+	for _, f := range files {
+		found := placeholders(f.name)
+		if !isBinary(string(f.content)) {
+			found = append(found, placeholders(string(f.content))...)
+		}
+		for _, name := range found {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// fillPlaceholders replaces the placeholders of the paths and contents of the
+// files with their values.
+func fillPlaceholders(files []snippetFile, values placeholderValues) []snippetFile {
+	fill := func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+			return values[placeholderPattern.FindStringSubmatch(match)[1]]
+		})
+	}
+	filled := make([]snippetFile, len(files))
+	for i, f := range files {
+		f.name = fill(f.name)
+		if !isBinary(string(f.content)) {
+			f.content = []byte(fill(string(f.content)))
+		}
+		filled[i] = f
+	}
+	return filled
+}
+
+// askPlaceholders asks the user for the values of the placeholders missing
+// from values.
+func askPlaceholders(names []string, values placeholderValues, in *bufio.Reader, out io.Writer) error {
+	for _, name := range names {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		fmt.Fprintf(out, "%s: ", name)
+		answer, err := in.ReadString('\n')
+		if err != nil && answer == "" {
+			return fmt.Errorf("missing value for placeholder %q", name)
+		}
+		values[name] = strings.TrimRight(answer, "\r\n")
+	}
+	return nil
+}
+
+// runUse runs the `nap use` subcommand, writing a snippet, or all the
+// snippets of a folder given as folder/, to the current directory or dest
+// with their placeholders expanded, and returns the exit code.
+func runUse(args []string, config Config, snippets []Snippet) int {
+	flags := flag.NewFlagSet("use", flag.ContinueOnError)
+	exact := flags.Bool("exact", false, "only use the snippet at the exact folder/name.ext")
+	force := flags.Bool("force", false, "overwrite existing files")
+	values := placeholderValues{}
+	flags.Var(values, "set", "value of a placeholder, as name=value (repeatable)")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("usage: nap use [--exact] [--force] [--set name=value] <snippet|folder/> [dest]")
+		return 2
+	}
+
+	dir := "."
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	var files []snippetFile
+	var used []Snippet
+	if strings.HasSuffix(args[0], "/") {
+		files, used, err = templateFiles(config, snippets, strings.TrimSuffix(args[0], "/"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		snippet, ok := lookupSnippet(args[0], snippets, *exact)
+		if !ok {
+			return 1
+		}
+		if files, err = snippetFiles(config, snippet); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		used = []Snippet{snippet}
+		// a single file may be written under another name
+		info, err := os.Stat(dir)
+		if len(args) == 2 && !snippet.Multi && !strings.HasSuffix(dir, "/") && (err != nil || !info.IsDir()) {
+			dir, files[0].name = filepath.Dir(dir), filepath.Base(dir)
+		}
+	}
+
+	names := filePlaceholders(files)
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		if err := askPlaceholders(names, values, bufio.NewReader(os.Stdin), os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			fmt.Fprintf(os.Stderr, "missing value for placeholder %q, use --set %s=value\n", name, name)
+			return 1
+		}
+	}
+
+	paths, err := writeFiles(dir, fillPlaceholders(files, values), *force)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, path := range paths {
+		fmt.Printf("wrote %s\n", path)
+	}
+	for _, s := range used {
+		recordUsage(s, usagePrint)
+	}
+	return 0
+}
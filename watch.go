@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/exp/slices"
+)
+
+// watchDebounce is how long the home directory must stay quiet before the
+// changes made to it are reloaded, so that a git pull reloads once.
+const watchDebounce = 200 * time.Millisecond
+
+// homeChangedMsg is sent when snippets changed in the home directory.
+type homeChangedMsg struct{}
+
+// homeWatcher watches the home directory for snippets changed outside nap.
+type homeWatcher struct {
+	config  Config
+	home    string
+	ignore  ignoreRules
+	watcher *fsnotify.Watcher
+	changes chan struct{}
+}
+
+// watchHome starts watching the home directory, its folders and the
+// directories of multi-file snippets.
+func watchHome(config Config) (*homeWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &homeWatcher{
+		config:  config,
+		home:    config.Home,
+		ignore:  readIgnore(config),
+		watcher: watcher,
+		changes: make(chan struct{}, 1),
+	}
+	if err := w.addTree(config.Home); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// addTree watches the directory and the directories under it, leaving out
+// hidden ones like the trash.
+func (w *homeWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+// relevant reports whether the event may change the snippets. Hidden and
+// ignored files, like editor swap files, are left out, and so are the files
+// at the root of the home directory other than the snippets file, whose
+// metadata other nap commands write. A change to .napignore reads the rules
+// again.
+func (w *homeWatcher) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	rel, err := filepath.Rel(w.home, event.Name)
+	if err != nil || rel == "." {
+		return false
+	}
+	if rel == filepath.Clean(w.config.File) {
+		return true
+	}
+	if rel == napignoreFile {
+		w.ignore = readIgnore(w.config)
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	info, err := os.Stat(event.Name)
+	if w.ignore.ignored(filepath.ToSlash(rel), err == nil && info.IsDir()) {
+		return false
+	}
+	if filepath.Dir(rel) == "." {
+		// a removed entry may have been a folder
+		return err != nil || info.IsDir()
+	}
+	return true
+}
+
+// run forwards the changes of the home directory once it stays quiet for
+// watchDebounce, until the watcher is closed.
+func (w *homeWatcher) run() {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = w.addTree(event.Name)
+				}
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(watchDebounce, w.notify)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+		case _, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// notify records a change, which is reported once however many happen
+// before it is received.
+func (w *homeWatcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// wait returns a Cmd waiting for the next change of the home directory.
+func (w *homeWatcher) wait() tea.Cmd {
+	return func() tea.Msg {
+		<-w.changes
+		return homeChangedMsg{}
+	}
+}
+
+// Close stops watching the home directory.
+func (w *homeWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// snippetsByPath returns the snippets keyed by their path.
+func snippetsByPath(snippets []Snippet) map[string]Snippet {
+	byPath := make(map[string]Snippet, len(snippets))
+	for _, s := range snippets {
+		byPath[s.Path()] = s
+	}
+	return byPath
+}
+
+// sameMetadata reports whether the snippets have the same metadata, leaving
+// out their position in the folder.
+func sameMetadata(a, b Snippet) bool {
+	return a.ID == b.ID && a.Date.Equal(b.Date) && a.Folder == b.Folder &&
+		a.Name == b.Name && a.File == b.File && a.Language == b.Language &&
+		slices.Equal(a.Tags, b.Tags) && a.Favorite == b.Favorite &&
+		a.Description == b.Description && a.Source == b.Source && a.Hash == b.Hash &&
+		a.Attachment == b.Attachment && a.Multi == b.Multi
+}
+
+// reloadHome adds the snippets created in the home directory outside nap,
+// removes those deleted and displays the selected snippet again. The metadata
+// other nap commands wrote to the snippets file since it was last read is
+// kept, like the tags and description of a snippet added by `nap add`.
+func (m *Model) reloadHome() tea.Cmd {
+	stored := snippetsByPath(readSnippets(m.config))
+	snippets := m.allSnippets()
+	found, _ := findNewSnippets(m.config, snippets)
+	missing := findMissingSnippets(m.config, snippets)
+
+	var updated int
+	for _, s := range snippets {
+		saved, ok := stored[s.Path()]
+		if prev, known := m.stored[s.Path()]; !ok || (known && sameMetadata(prev, saved)) || sameMetadata(s, saved) {
+			continue
+		}
+		folder, idx, _ := m.locateSnippet(s.Path())
+		saved.Order = s.Order
+		m.Lists[folder].SetItem(idx, saved)
+		updated++
+	}
+	m.stored = stored
+	if len(found) == 0 && len(missing) == 0 && updated == 0 {
+		return m.updateContent()
+	}
+	// the operations refer to list positions and snippets the reload may have
+	// moved or removed, so they can no longer be undone safely
+	m.history = history{}
+
+	for _, s := range missing {
+		m.removeSnippet(s.Path())
+	}
+	for _, s := range found {
+		if saved, ok := stored[s.Path()]; ok {
+			saved.Order = s.Order
+			s = saved
+		}
+		idx := 0
+		if li, ok := m.Lists[Folder(s.Folder)]; ok {
+			idx = len(li.Items())
+		}
+		m.insertSnippet(idx, s)
+	}
+	// folders removed with their snippets
+	for _, folder := range m.snippetFolders() {
+		_, err := os.Stat(filepath.Join(m.config.Home, string(folder)))
+		if errors.Is(err, fs.ErrNotExist) && len(m.Lists[folder].Items()) == 0 && folder != m.selectedFolder() {
+			delete(m.Lists, folder)
+		}
+	}
+	m.sortLists()
+	m.selectFolder(m.selectedFolder())
+	m.updateUsageViews()
+	m.updateKeyMap()
+
+	status := fmt.Sprintf("Reloaded: %d added, %d removed, %d updated", len(found), len(missing), updated)
+	return tea.Batch(m.List().NewStatusMessage(status), m.updateContent())
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/exp/slices"
+)
+
+func TestWatchHome(t *testing.T) {
+	tmp := tmpHome(t)
+	if err := os.MkdirAll(filepath.Join(tmp, "misc"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "misc", "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	cfg := readConfig()
+	m := newModel(cfg, scanSnippets(cfg, nil), State{})
+	w, err := watchHome(cfg)
+	if err != nil {
+		t.Logf("could not watch home: %v", err)
+		t.FailNow()
+	}
+	defer w.Close()
+
+	tests := []struct {
+		name     string
+		relevant bool
+	}{
+		{filepath.Join(tmp, cfg.File), true},
+		{filepath.Join(tmp, "misc", ".a.go.swp"), false},
+		{filepath.Join(tmp, ".trash", "a.go"), false},
+		{filepath.Join(tmp, "misc", "b.go"), true},
+		{filepath.Join(tmp, "misc", "b.go~"), false},
+		{filepath.Join(tmp, "removed"), true},
+	}
+	for _, test := range tests {
+		if got := w.relevant(fsnotify.Event{Name: test.name, Op: fsnotify.Write}); got != test.relevant {
+			t.Logf("relevant(%s) = %v, want %v", test.name, got, test.relevant)
+			t.FailNow()
+		}
+	}
+
+	changed := make(chan struct{})
+	go func() {
+		w.wait()()
+		close(changed)
+	}()
+	if err := os.MkdirAll(filepath.Join(tmp, "new", "stack"), os.ModePerm); err != nil {
+		t.Logf("could not create folder: %v", err)
+		t.FailNow()
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "misc", "b.go"), []byte("package b\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Log("expected the change to be reported")
+		t.FailNow()
+	}
+
+	if err := os.Remove(filepath.Join(tmp, "misc", "a.go")); err != nil {
+		t.Logf("could not remove snippet: %v", err)
+		t.FailNow()
+	}
+	m.history.push(&moveOp{folder: "misc", snippet: filepath.Join("misc", "a.go"), from: 0, to: 1})
+	m.reloadHome()
+	if len(m.history.done) != 0 {
+		t.Log("expected the history to be cleared when the snippets changed")
+		t.FailNow()
+	}
+	if _, _, ok := m.locateSnippet(filepath.Join("misc", "b.go")); !ok {
+		t.Log("expected the new snippet to be added")
+		t.FailNow()
+	}
+	if _, _, ok := m.locateSnippet(filepath.Join("new", "stack")); !ok {
+		t.Log("expected the snippet of the new folder to be added")
+		t.FailNow()
+	}
+	if _, _, ok := m.locateSnippet(filepath.Join("misc", "a.go")); ok {
+		t.Log("expected the removed snippet to be removed")
+		t.FailNow()
+	}
+
+	// metadata written by nap add in another terminal
+	if err := os.WriteFile(filepath.Join(tmp, "misc", "c.go"), []byte("package c\n"), 0o644); err != nil {
+		t.Logf("could not create snippet: %v", err)
+		t.FailNow()
+	}
+	stored := m.allSnippets()
+	for i := range stored {
+		if stored[i].Path() == filepath.Join("misc", "b.go") {
+			stored[i].Description = "package b"
+		}
+	}
+	stored = append(stored, Snippet{Folder: "misc", Name: "c", File: "c.go", Language: "go", Tags: []string{"added"}, Source: "/src/c.go"})
+	writeSnippets(cfg, stored)
+	m.reloadHome()
+	folder, idx, ok := m.locateSnippet(filepath.Join("misc", "c.go"))
+	if s := m.Lists[folder].Items()[idx].(Snippet); !ok || !slices.Equal(s.Tags, []string{"added"}) || s.Source != "/src/c.go" {
+		t.Logf("expected the new snippet to keep its metadata, got %+v", s)
+		t.FailNow()
+	}
+	folder, idx, _ = m.locateSnippet(filepath.Join("misc", "b.go"))
+	if s := m.Lists[folder].Items()[idx].(Snippet); s.Description != "package b" {
+		t.Logf("expected the changed metadata to be reloaded, got %+v", s)
+		t.FailNow()
+	}
+
+	// metadata changed here is kept when the file did not change it
+	s := m.Lists[folder].Items()[idx].(Snippet)
+	s.Description = "edited"
+	m.Lists[folder].SetItem(idx, s)
+	m.history.push(&metadataOp{name: "edit"})
+	m.reloadHome()
+	if s := m.Lists[folder].Items()[idx].(Snippet); s.Description != "edited" {
+		t.Logf("expected the metadata changed here to be kept, got %+v", s)
+		t.FailNow()
+	}
+	if len(m.history.done) != 1 {
+		t.Log("expected the history to be kept when nothing changed")
+		t.FailNow()
+	}
+}